@@ -0,0 +1,80 @@
+package logger
+
+import (
+	"math/rand/v2"
+	"time"
+)
+
+const (
+	defaultBackoffInitial    = 500 * time.Millisecond
+	defaultBackoffMax        = 30 * time.Second
+	defaultBackoffMultiplier = 2
+	defaultDialTimeout       = 5 * time.Second
+)
+
+// Backoff 重连退避策略，连续失败时等待时间从 Initial 开始按 Multiplier 倍数增长，最长 Max
+type Backoff struct {
+	Initial    time.Duration `json:"initial"`    //首次重连等待时间，默认500ms
+	Max        time.Duration `json:"max"`        //最长等待时间，默认30s
+	Multiplier float64       `json:"multiplier"` //增长倍数，默认2
+	Jitter     float64       `json:"jitter"`     //随机抖动比例(0~1)，例如0.2表示在 ±20% 范围内随机，避免多个进程同时重连
+}
+
+// Next 根据上一次的等待时间计算下一次的等待时间，不包含抖动
+func (b *Backoff) Next(prev time.Duration) time.Duration {
+	initial, max, multiplier := b.Initial, b.limit(), b.Multiplier
+	if initial <= 0 {
+		initial = defaultBackoffInitial
+	}
+	if multiplier < 1 {
+		multiplier = defaultBackoffMultiplier
+	}
+	if prev <= 0 {
+		return min(initial, max)
+	}
+	next := time.Duration(float64(prev) * multiplier)
+	if next <= 0 || next > max {
+		next = max
+	}
+	return next
+}
+
+// limit 最长等待时间
+func (b *Backoff) limit() time.Duration {
+	if b.Max <= 0 {
+		return defaultBackoffMax
+	}
+	return b.Max
+}
+
+// jitter 为等待时间增加随机抖动
+func (b *Backoff) jitter(d time.Duration) time.Duration {
+	if b.Jitter <= 0 || d <= 0 {
+		return d
+	}
+	j := min(b.Jitter, 1)
+	return time.Duration(float64(d) * (1 + j*(rand.Float64()*2-1)))
+}
+
+// BackoffPolicy 重连等待期间新日志的处理策略
+type BackoffPolicy int8
+
+const (
+	BackoffQueue BackoffPolicy = 0 // 保留在队列中等待重连，队列满时丢弃新日志
+	BackoffDrop  BackoffPolicy = 1 // 直接丢弃
+)
+
+// ConnState 连接状态
+type ConnState int8
+
+const (
+	ConnStateDisconnected ConnState = 0
+	ConnStateConnected    ConnState = 1
+)
+
+func (s ConnState) String() string {
+	if s == ConnStateConnected {
+		return "connected"
+	}
+	return "disconnected"
+}
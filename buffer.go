@@ -0,0 +1,71 @@
+package logger
+
+import (
+	"strconv"
+	"sync"
+	"time"
+)
+
+const maxPooledBufferSize = 64 * 1024 // 超过该容量的缓冲不回收，避免长期占用内存
+
+var bufferPool = sync.Pool{New: func() any {
+	return &Buffer{B: make([]byte, 0, 1024)}
+}}
+
+// Buffer 可复用的字节缓冲，由 NewBuffer 从缓冲池获取，使用完毕后调用 Free 归还
+type Buffer struct {
+	B []byte
+}
+
+func NewBuffer() *Buffer {
+	b := bufferPool.Get().(*Buffer)
+	b.B = b.B[:0]
+	return b
+}
+
+// Free 归还缓冲池，调用后不能再使用该缓冲
+func (b *Buffer) Free() {
+	if cap(b.B) > maxPooledBufferSize {
+		return
+	}
+	bufferPool.Put(b)
+}
+
+func (b *Buffer) Write(p []byte) (int, error) {
+	b.B = append(b.B, p...)
+	return len(p), nil
+}
+
+func (b *Buffer) WriteString(s string) (int, error) {
+	b.B = append(b.B, s...)
+	return len(s), nil
+}
+
+func (b *Buffer) WriteByte(c byte) error {
+	b.B = append(b.B, c)
+	return nil
+}
+
+func (b *Buffer) AppendInt(i int64) {
+	b.B = strconv.AppendInt(b.B, i, 10)
+}
+
+func (b *Buffer) AppendTime(t time.Time, layout string) {
+	b.B = t.AppendFormat(b.B, layout)
+}
+
+func (b *Buffer) Bytes() []byte {
+	return b.B
+}
+
+func (b *Buffer) String() string {
+	return string(b.B)
+}
+
+func (b *Buffer) Len() int {
+	return len(b.B)
+}
+
+func (b *Buffer) Reset() {
+	b.B = b.B[:0]
+}
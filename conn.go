@@ -1,117 +1,454 @@
 package logger
 
 import (
+	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
-	"io"
 	"net"
 	"os"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
-func NewConn(network, address string) *Conn {
-	return &Conn{Network: network, Address: address}
+const (
+	defaultConnQueueSize = 1000
+	connStateUnknown     = ConnState(-1)
+)
+
+var (
+	errConnUnavailable = errors.New("logger conn unavailable")
+	errConnDropped     = errors.New("logger conn message dropped") //transmit 无法发送的日志，计入 Dropped 并且不会重连
+)
+
+func NewConn(network, address string, cap ...int) *Conn {
+	c := &Conn{Network: network, Address: address}
+	if len(cap) > 0 {
+		c.QueueSize = cap[0]
+	}
+	return c
 }
 
+// Conn 网络日志输出，日志在调用方协程中编码后放入队列，由独立的发送协程写入网络
+// 队列满时丢弃新日志，网络缓慢或者日志服务不可用时不会阻塞业务逻辑
+// 连接失败后按 Backoff 退避重连，连接状态变化时通过 OnState 通知一次
 type Conn struct {
-	sync.Mutex
-	Network     string `json:"network"`
-	Address     string `json:"address"`
-	Reconnect   bool   `json:"reconnect"`
-	Format      func(*Message) string
-	innerWriter io.WriteCloser
-	illNetFlag  bool //网络异常标记
+	Network      string                                        `json:"network"`
+	Address      string                                        `json:"address"`      //日志服务地址，多个地址使用 ; 分隔
+	QueueSize    int                                           `json:"queueSize"`    //队列容量，默认1000
+	Reconnect    bool                                          `json:"reconnect"`    //Deprecated: 网络异常后总是按 Backoff 自动重连
+	Backoff      Backoff                                       `json:"backoff"`      //重连退避策略
+	Policy       BackoffPolicy                                 `json:"policy"`       //重连等待期间日志的处理策略,默认 BackoffQueue
+	TLS          *TLSOptions                                   `json:"tls"`          //TLS配置，Network 为 tls 时使用
+	DialTimeout  time.Duration                                 `json:"dialTimeout"`  //连接超时，默认5秒
+	WriteTimeout time.Duration                                 `json:"writeTimeout"` //写超时，默认5秒
+	Strategy     Strategy                                      `json:"strategy"`     //多个地址时的发送策略，默认 StrategyFailover
+	Fallback     time.Duration                                 `json:"fallback"`     //StrategyFailover 使用备用地址时检测主地址的间隔，默认30秒
+	Spool        string                                        `json:"spool"`        //磁盘缓存目录，设置后网络不可用时日志写入该目录，恢复后按顺序重发
+	SpoolLimit   int64                                         `json:"spoolLimit"`   //磁盘缓存容量上限(byte)，默认100M，超过时删除最旧的日志
+	OnState      func(addr string, state ConnState, err error) //连接状态变化回调，未设置时输出到stderr
+	Encoder      Encoder                                       //编码器,默认 ContentEncoder
+	Format       func(*Message) string                         //Deprecated: 使用 Encoder, 设置后优先于 Encoder
+
+	once      sync.Once
+	mutex     sync.RWMutex                        //保护closed和queue的关闭
+	closed    bool                                //是否已经关闭
+	queue     chan *Buffer                        //发送队列
+	flush     chan chan error                     //刷新请求,由process处理后返回结果
+	closing   chan struct{}                       //Close时关闭，通知process停止等待重连
+	done      chan struct{}                       //process退出后关闭
+	dropped   atomic.Int64                        //队列已满或者发送失败丢弃的日志数量
+	endpoints []*endpoint                         //日志服务地址，只在process中使用
+	spool     *spool                              //磁盘缓存，只在process中使用
+	current   int                                 //StrategyFailover 当前使用的地址，StrategyRoundRobin 下一个地址
+	tlsConfig *tls.Config                         //由 TLS 生成
+	tlsError  error                               //生成 tlsConfig 的错误，连接时返回
+	pack      func(buf *Buffer, msg *Message)     //编码并分帧，设置后替代 Encoder 和换行分隔，供 Syslog 等输出使用
+	transmit  func(conn net.Conn, p []byte) error //写入连接，设置后替代 conn.Write，供 GELF 分块发送等使用
+	setup     func()                              //启动发送协程前调用，供嵌入 Conn 的输出读取配置
+	bundle    func(bufs []*Buffer) *Buffer        //将多条日志合并成一次发送的内容，供 Fluent 批量发送使用
+	batch     int                                 //bundle 每次最多合并的日志条数
+}
+
+// endpoint 单个日志服务地址的连接和重连状态
+type endpoint struct {
+	addr     string
+	conn     net.Conn
+	state    ConnState
+	backoff  time.Duration //当前退避时间
+	retryAt  time.Time     //下次允许重连的时间
+	dialAt   time.Time     //最近一次连接的时间
+	health   atomic.Int32  //ConnState，供 Endpoints 读取
+	sent     atomic.Int64  //发送成功的日志数量
+	failures atomic.Int64  //连接或者发送失败的次数
 }
 
 func (c *Conn) Name() string {
 	return c.Network + "://" + c.Address
 }
 
+// Init 启动发送协程，未调用时首次写入会自动启动，启动后不能再修改配置
 func (c *Conn) Init() error {
-	if c.innerWriter != nil {
-		_ = c.innerWriter.Close()
-		c.innerWriter = nil
+	c.start()
+	return nil
+}
+
+func (c *Conn) start() {
+	c.once.Do(func() {
+		if c.setup != nil {
+			c.setup()
+		}
+		size := c.QueueSize
+		if size <= 0 {
+			size = defaultConnQueueSize
+		}
+		for _, addr := range strings.Split(c.Address, ";") {
+			if addr = strings.TrimSpace(addr); addr != "" {
+				ep := &endpoint{addr: addr, state: connStateUnknown}
+				ep.health.Store(int32(ConnStateDisconnected))
+				c.endpoints = append(c.endpoints, ep)
+			}
+		}
+		if c.Network == networkTLS {
+			options := c.TLS
+			if options == nil {
+				options = &TLSOptions{}
+			}
+			c.tlsConfig, c.tlsError = options.Config()
+		}
+		if c.Spool != "" {
+			var err error
+			if c.spool, err = openSpool(c.Spool, c.SpoolLimit); err != nil {
+				_, _ = fmt.Fprintf(os.Stderr, "logger conn open spool error:%v\n", err)
+			}
+		}
+		c.queue = make(chan *Buffer, size)
+		c.flush = make(chan chan error)
+		c.closing = make(chan struct{})
+		c.done = make(chan struct{})
+		go c.process()
+	})
+}
+
+// Dropped 丢弃的日志数量
+func (c *Conn) Dropped() int64 {
+	return c.dropped.Load()
+}
+
+func (c *Conn) Write(msg *Message) {
+	c.start()
+	buf := NewBuffer()
+	c.encode(buf, msg)
+
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	if c.closed {
+		buf.Free()
+		return
+	}
+	select {
+	case c.queue <- buf:
+	default:
+		buf.Free()
+		c.dropped.Add(1)
 	}
+}
+
+func (c *Conn) encode(buf *Buffer, msg *Message) {
+	if c.pack != nil {
+		c.pack(buf, msg)
+		return
+	}
+	if c.Format != nil {
+		buf.WriteString(c.Format(msg))
+		if msg.Level >= LevelError {
+			buf.WriteString("\n")
+			buf.WriteString(msg.Stack)
+		}
+	} else if c.Encoder != nil {
+		encode(c.Encoder, buf, msg)
+	} else {
+		encode(defaultContentEncoder, buf, msg)
+	}
+	buf.WriteByte('\n')
+}
+
+// Flush 等待队列中已有的日志发送完成，日志服务不可用时返回错误
+func (c *Conn) Flush(ctx context.Context) error {
+	c.start()
+	result := make(chan error, 1)
+	select {
+	case c.flush <- result:
+	case <-c.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case err := <-result:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close 发送队列中剩余的日志后关闭连接，可以重复调用
+// 日志服务不可用时只会忽略退避时间尝试重连一次，失败则丢弃剩余日志
+func (c *Conn) Close() error {
+	c.start()
+	c.mutex.Lock()
+	if !c.closed {
+		c.closed = true
+		close(c.queue)
+		close(c.closing)
+	}
+	c.mutex.Unlock()
+	<-c.done
 	return nil
 }
 
-func (c *Conn) Write(msg *Message) (err error) {
-	if c.needToConnectOnMsg() {
-		err = c.connect()
-		if err != nil {
+func (c *Conn) process() {
+	defer close(c.done)
+	defer func() {
+		for _, ep := range c.endpoints {
+			if ep.conn != nil {
+				_ = ep.conn.Close()
+				ep.conn = nil
+			}
+		}
+		if c.spool != nil {
+			c.spool.close()
+		}
+	}()
+	timer := time.NewTimer(time.Hour)
+	timer.Stop()
+	defer timer.Stop()
+
+	var pending *Buffer //等待重连的日志
+	for {
+		var queue <-chan *Buffer
+		var retry <-chan time.Time
+		var closing <-chan struct{}
+		if pending == nil {
+			queue = c.queue
+		} else {
+			closing = c.closing
+		}
+		if pending != nil || c.spooled() {
+			timer.Reset(c.retryDelay())
+			retry = timer.C
+		}
+		select {
+		case b, ok := <-queue:
+			if !ok {
+				return
+			}
+			pending = c.collect(b)
+		case <-retry:
+			if pending == nil {
+				c.replay()
+			}
+		case <-closing:
+			c.shutdown(pending)
 			return
+		case result := <-c.flush:
+			result <- c.flushQueue(&pending)
+		}
+		timer.Stop()
+		if pending != nil && c.deliver(pending, true) {
+			pending = nil
 		}
-		c.illNetFlag = false
 	}
-	//网络异常时，消息发出
-	if !c.illNetFlag {
-		err = c.println(msg)
-		//网络异常，通知处理网络的go程自动重连
-		if err != nil {
-			c.illNetFlag = true
+}
+
+// flushQueue 发送等待中的日志和队列中已有的日志，日志服务不可用时返回错误
+// 使用磁盘缓存时，写入磁盘缓存的日志视为已经刷新
+func (c *Conn) flushQueue(pending **Buffer) error {
+	if *pending != nil {
+		if !c.deliver(*pending, true) {
+			return errConnUnavailable
+		}
+		*pending = nil
+	}
+	for len(c.queue) > 0 {
+		b, ok := <-c.queue
+		if !ok {
+			break
+		}
+		if b = c.collect(b); !c.deliver(b, true) {
+			*pending = b
+			return errConnUnavailable
 		}
 	}
-	return
+	return nil
 }
 
-func (c *Conn) Close() {
-	if c.innerWriter != nil {
-		_ = c.innerWriter.Close()
+// shutdown 关闭时忽略退避时间重连一次，发送剩余日志，无法发送的日志写入磁盘缓存或者丢弃
+func (c *Conn) shutdown(pending *Buffer) {
+	for _, ep := range c.endpoints {
+		ep.retryAt = time.Time{}
+	}
+	if pending != nil {
+		c.deliver(pending, false)
+	}
+	for b := range c.queue {
+		c.deliver(c.collect(b), false)
 	}
 }
 
-func (c *Conn) connect() error {
-	if c.innerWriter != nil {
-		_ = c.innerWriter.Close()
-		c.innerWriter = nil
+// collect 设置了 bundle 时，从队列中取出已有的日志，与 b 合并成一次发送的内容
+func (c *Conn) collect(b *Buffer) *Buffer {
+	if c.bundle == nil {
+		return b
 	}
-	addrs := strings.Split(c.Address, ";")
-	for _, addr := range addrs {
-		conn, err := net.Dial(c.Network, addr)
-		if err != nil {
-			_, _ = fmt.Fprintf(os.Stderr, "net.Dial error:%v\n", err)
-			continue
-			//return err
+	bufs := []*Buffer{b}
+	for len(bufs) < c.batch && len(c.queue) > 0 {
+		next, ok := <-c.queue
+		if !ok {
+			break
 		}
+		bufs = append(bufs, next)
+	}
+	r := c.bundle(bufs)
+	for _, v := range bufs {
+		v.Free()
+	}
+	return r
+}
 
-		if tcpConn, ok := conn.(*net.TCPConn); ok {
-			_ = tcpConn.SetKeepAlive(true)
+// deliver 发送一条日志，返回 false 表示日志服务暂不可用并且需要等待重连后再次发送
+// 所有地址都无法发送时写入磁盘缓存
+// 未设置磁盘缓存时，wait 为 false 或者策略为 BackoffDrop 时直接丢弃
+func (c *Conn) deliver(b *Buffer, wait bool) bool {
+	// 磁盘缓存中有日志时，需要先发送缓存中的日志以保证顺序
+	if c.spooled() {
+		if c.replay(); c.spooled() {
+			c.save(b)
+			return true
 		}
-		c.innerWriter = conn
-		return nil
 	}
-	return fmt.Errorf("hava no valid logs service addr:%v", c.Address)
+	if c.send(b.B) {
+		b.Free()
+		return true
+	}
+	if c.spool != nil {
+		c.save(b)
+		return true
+	}
+	if wait && c.Policy == BackoffQueue && len(c.endpoints) > 0 {
+		return false
+	}
+	b.Free()
+	c.dropped.Add(1)
+	return true
 }
 
-func (c *Conn) needToConnectOnMsg() bool {
-	if c.Reconnect {
-		c.Reconnect = false
-		return true
+func (c *Conn) spooled() bool {
+	return c.spool != nil && !c.spool.empty()
+}
+
+// save 写入磁盘缓存，超过容量删除的日志和写入失败的日志计入丢弃数量
+func (c *Conn) save(b *Buffer) {
+	defer b.Free()
+	evicted, err := c.spool.append(b.B)
+	if err != nil {
+		evicted++
 	}
+	c.dropped.Add(evicted)
+}
 
-	if c.innerWriter == nil {
-		return true
+// replay 按顺序发送磁盘缓存中的日志，日志服务不可用时停止
+func (c *Conn) replay() {
+	for c.spooled() {
+		p, err := c.spool.peek()
+		if err != nil || !c.send(p) {
+			return
+		}
+		c.spool.pop(p)
 	}
+}
 
-	if c.illNetFlag {
-		return true
+// retryDelay 距离最近一次允许重连的时间
+func (c *Conn) retryDelay() time.Duration {
+	var r time.Duration = -1
+	now := time.Now()
+	for _, ep := range c.endpoints {
+		d := ep.retryAt.Sub(now)
+		if r < 0 || d < r {
+			r = d
+		}
 	}
-	return false
-	//return c.Options.ReconnectOnMsg
+	return max(r, time.Millisecond)
 }
 
-func (c *Conn) println(msg *Message) (err error) {
-	c.Lock()
-	defer c.Unlock()
-	var txt string
-	if c.Format != nil {
-		txt = c.Format(msg)
+func (c *Conn) dial(ep *endpoint) error {
+	timeout := c.DialTimeout
+	if timeout <= 0 {
+		timeout = defaultDialTimeout
+	}
+	ep.dialAt = time.Now()
+	var conn net.Conn
+	var err error
+	dialer := &net.Dialer{Timeout: timeout}
+	if c.Network == networkTLS {
+		if err = c.tlsError; err == nil {
+			conn, err = tls.DialWithDialer(dialer, "tcp", ep.addr, c.tlsConfig)
+		}
 	} else {
-		txt = msg.Content
+		conn, err = dialer.Dial(c.Network, ep.addr)
+	}
+	if err != nil {
+		return err
 	}
-	if msg.Level >= LevelError {
-		txt = txt + "\n" + msg.Stack
+	ep.conn = conn
+	ep.backoff = 0
+	ep.retryAt = time.Time{}
+	c.setState(ep, ConnStateConnected, nil)
+	return nil
+}
+
+func (c *Conn) write(ep *endpoint, b []byte) error {
+	timeout := c.WriteTimeout
+	if timeout <= 0 {
+		timeout = defaultDialTimeout
+	}
+	_ = ep.conn.SetWriteDeadline(time.Now().Add(timeout))
+	var err error
+	if c.transmit != nil {
+		err = c.transmit(ep.conn, b)
+	} else {
+		_, err = ep.conn.Write(b)
+	}
+	if err == nil {
+		ep.sent.Add(1)
 	}
-	_, err = c.innerWriter.Write(append([]byte(txt), '\n'))
 	return err
 }
+
+// fail 关闭连接并计算下次重连时间
+func (c *Conn) fail(ep *endpoint, err error) {
+	if ep.conn != nil {
+		_ = ep.conn.Close()
+		ep.conn = nil
+	}
+	ep.failures.Add(1)
+	ep.backoff = c.Backoff.Next(ep.backoff)
+	ep.retryAt = time.Now().Add(c.Backoff.jitter(ep.backoff))
+	c.setState(ep, ConnStateDisconnected, err)
+}
+
+// setState 连接状态变化时通知一次
+func (c *Conn) setState(ep *endpoint, state ConnState, err error) {
+	if ep.state == state {
+		return
+	}
+	ep.state = state
+	ep.health.Store(int32(state))
+	if c.OnState != nil {
+		c.OnState(ep.addr, state, err)
+	} else if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "logger conn %v://%v %v:%v\n", c.Network, ep.addr, state, err)
+	}
+}
@@ -0,0 +1,330 @@
+package logger
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// listenLines 启动本地TCP服务，按行接收日志
+func listenLines(t *testing.T) (net.Listener, chan string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	lines := make(chan string, 1000)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				scanner := bufio.NewScanner(conn)
+				for scanner.Scan() {
+					lines <- scanner.Text()
+				}
+			}()
+		}
+	}()
+	t.Cleanup(func() { _ = ln.Close() })
+	return ln, lines
+}
+
+func receiveLine(t *testing.T, lines chan string) string {
+	t.Helper()
+	select {
+	case line := <-lines:
+		return line
+	case <-time.After(3 * time.Second):
+		t.Fatalf("receive line timeout")
+	}
+	return ""
+}
+
+func TestConnOutput(t *testing.T) {
+	ln, lines := listenLines(t)
+	log := New()
+	log.SetLevel(LevelDebug)
+	c := &Conn{Network: "tcp", Address: ln.Addr().String()}
+	if err := log.SetOutput(c.Name(), c); err != nil {
+		t.Fatalf("SetOutput: %v", err)
+	}
+	for i := 0; i < 10; i++ {
+		log.Infow("hello", "i", i)
+	}
+	if err := log.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	for i := 0; i < 10; i++ {
+		if line := receiveLine(t, lines); !strings.HasPrefix(line, "hello i=") {
+			t.Fatalf("unexpected line %q", line)
+		}
+	}
+	if err := log.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if err := c.Close(); err != nil {
+		t.Fatalf("second Close: %v", err)
+	}
+	c.Write(&Message{Content: "after close"})
+}
+
+// unusedAddr 先占用再释放端口，得到一个没有服务监听的地址
+func unusedAddr(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	addr := ln.Addr().String()
+	_ = ln.Close()
+	return addr
+}
+
+func TestConnNonBlocking(t *testing.T) {
+	c := &Conn{Network: "tcp", Address: unusedAddr(t), QueueSize: 10, OnState: func(string, ConnState, error) {}}
+	defer c.Close()
+	start := time.Now()
+	for i := 0; i < 1000; i++ {
+		c.Write(&Message{Level: LevelInfo, Content: "lost"})
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("Write blocked %v", d)
+	}
+	if err := c.Flush(context.Background()); err != errConnUnavailable {
+		t.Errorf("Flush = %v", err)
+	}
+	if n := c.Dropped(); n < 1000-11 {
+		t.Errorf("Dropped = %d", n)
+	}
+}
+
+func TestConnBackoff(t *testing.T) {
+	addr := unusedAddr(t)
+	var mutex sync.Mutex
+	var states []ConnState
+	c := &Conn{
+		Network: "tcp",
+		Address: addr,
+		Backoff: Backoff{Initial: 20 * time.Millisecond, Max: 50 * time.Millisecond, Jitter: 0.2},
+		OnState: func(_ string, state ConnState, _ error) {
+			mutex.Lock()
+			states = append(states, state)
+			mutex.Unlock()
+		},
+	}
+	defer c.Close()
+	for i := 0; i < 5; i++ {
+		c.Write(&Message{Content: "queued"})
+	}
+	time.Sleep(200 * time.Millisecond)
+
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		t.Skipf("listen %v: %v", addr, err)
+	}
+	defer ln.Close()
+	received := make(chan string, 10)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		scanner := bufio.NewScanner(conn)
+		for scanner.Scan() {
+			received <- scanner.Text()
+		}
+	}()
+	for i := 0; i < 5; i++ {
+		if line := receiveLine(t, received); line != "queued" {
+			t.Fatalf("unexpected line %q", line)
+		}
+	}
+	mutex.Lock()
+	defer mutex.Unlock()
+	if len(states) != 2 || states[0] != ConnStateDisconnected || states[1] != ConnStateConnected {
+		t.Errorf("states = %v", states)
+	}
+}
+
+func TestConnBackoffDrop(t *testing.T) {
+	c := &Conn{Network: "tcp", Address: unusedAddr(t), Policy: BackoffDrop, OnState: func(string, ConnState, error) {}}
+	for i := 0; i < 5; i++ {
+		c.Write(&Message{Content: "dropped"})
+	}
+	if err := c.Flush(context.Background()); err != nil {
+		t.Errorf("Flush = %v", err)
+	}
+	_ = c.Close()
+	if c.Dropped() != 5 {
+		t.Errorf("Dropped = %d", c.Dropped())
+	}
+}
+
+func TestBackoffNext(t *testing.T) {
+	b := Backoff{Initial: time.Second, Max: 5 * time.Second}
+	var d time.Duration
+	var got []time.Duration
+	for i := 0; i < 5; i++ {
+		d = b.Next(d)
+		got = append(got, d)
+	}
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("Next sequence = %v, want %v", got, want)
+		}
+	}
+	b.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		if j := b.jitter(time.Second); j < 500*time.Millisecond || j > 1500*time.Millisecond {
+			t.Fatalf("jitter out of range: %v", j)
+		}
+	}
+}
+
+// acceptLines 在指定地址启动TCP服务，按行接收日志
+func acceptLines(t *testing.T, addr string) chan string {
+	t.Helper()
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		t.Skipf("listen %v: %v", addr, err)
+	}
+	t.Cleanup(func() { _ = ln.Close() })
+	lines := make(chan string, 1000)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				scanner := bufio.NewScanner(conn)
+				for scanner.Scan() {
+					lines <- scanner.Text()
+				}
+			}()
+		}
+	}()
+	return lines
+}
+
+func TestConnSpool(t *testing.T) {
+	addr, dir := unusedAddr(t), t.TempDir()
+	newConn := func() *Conn {
+		return &Conn{
+			Network: "tcp",
+			Address: addr,
+			Spool:   dir,
+			Backoff: Backoff{Initial: 10 * time.Millisecond, Max: 20 * time.Millisecond},
+			OnState: func(string, ConnState, error) {},
+		}
+	}
+	// 服务不可用时写入磁盘缓存，关闭后缓存保留在磁盘上
+	c := newConn()
+	for i := 0; i < 20; i++ {
+		c.Write(&Message{Content: fmt.Sprintf("line %d", i)})
+	}
+	if err := c.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	_ = c.Close()
+	if files, _ := filepath.Glob(filepath.Join(dir, "*"+spoolExt)); len(files) == 0 {
+		t.Fatalf("spool is empty")
+	}
+
+	// 服务恢复后按顺序重发，新日志在缓存之后发送
+	lines := acceptLines(t, addr)
+	c = newConn()
+	defer c.Close()
+	c.Write(&Message{Content: "line 20"})
+	for i := 0; i <= 20; i++ {
+		if line := receiveLine(t, lines); line != fmt.Sprintf("line %d", i) {
+			t.Fatalf("line %d = %q", i, line)
+		}
+	}
+	_ = c.Flush(context.Background())
+	if files, _ := filepath.Glob(filepath.Join(dir, "*"+spoolExt)); len(files) != 0 {
+		t.Errorf("spool not removed: %v", files)
+	}
+	if c.Dropped() != 0 {
+		t.Errorf("Dropped = %d", c.Dropped())
+	}
+}
+
+func TestSpoolEvict(t *testing.T) {
+	s, err := openSpool(t.TempDir(), 100)
+	if err != nil {
+		t.Fatalf("openSpool: %v", err)
+	}
+	defer s.close()
+	var evicted int64
+	for i := 0; i < 20; i++ {
+		n, err := s.append([]byte(fmt.Sprintf("record %02d", i)))
+		if err != nil {
+			t.Fatalf("append: %v", err)
+		}
+		evicted += n
+	}
+	if evicted == 0 || s.size > s.limit {
+		t.Fatalf("evicted = %d, size = %d", evicted, s.size)
+	}
+	// 剩余的记录是最新的一批，并且保持顺序
+	for i := int(evicted); i < 20; i++ {
+		p, err := s.peek()
+		if err != nil || string(p) != fmt.Sprintf("record %02d", i) {
+			t.Fatalf("record %d = %q, %v", i, p, err)
+		}
+		s.pop(p)
+	}
+	if !s.empty() {
+		t.Errorf("spool not empty")
+	}
+}
+
+func TestSpoolAppendError(t *testing.T) {
+	dir := t.TempDir()
+	s, err := openSpool(dir, 1<<20)
+	if err != nil {
+		t.Fatalf("openSpool: %v", err)
+	}
+	if _, err = s.append([]byte("record 1")); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+	// 写句柄失效时写入失败，之后的记录写入新的分段
+	_ = s.writer.Close()
+	if _, err = s.append([]byte("record 2")); err == nil {
+		t.Fatalf("append on closed segment should fail")
+	}
+	if _, err = s.append([]byte("record 3")); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+	s.close()
+
+	s, err = openSpool(dir, 1<<20)
+	if err != nil {
+		t.Fatalf("openSpool: %v", err)
+	}
+	defer s.close()
+	for _, want := range []string{"record 1", "record 3"} {
+		p, err := s.peek()
+		if err != nil || string(p) != want {
+			t.Fatalf("peek = %q, %v, want %q", p, err, want)
+		}
+		s.pop(p)
+	}
+	if !s.empty() {
+		t.Errorf("spool not empty")
+	}
+}
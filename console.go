@@ -1,7 +1,8 @@
 package logger
 
 import (
-	"fmt"
+	"bytes"
+	"os"
 	"runtime"
 	"strings"
 )
@@ -10,18 +11,16 @@ var Console = &console{colorful: true}
 
 func init() {
 	//简化默认控制台输出
-	Console.Sprintf = func(message *Message) *strings.Builder {
-		b := strings.Builder{}
-		b.WriteString(message.Content)
-		return &b
-	}
+	Console.Encoder = &ContentEncoder{}
 	if runtime.GOOS == "windows" {
 		Console.colorful = false
 	}
 }
 
 type console struct {
-	Disable  bool
+	Disable bool
+	Encoder Encoder
+	// Deprecated: 使用 Encoder, 设置后优先于 Encoder
 	Sprintf  func(*Message) *strings.Builder
 	colorful bool
 }
@@ -36,21 +35,30 @@ func (c *console) Write(msg *Message) {
 	if c.Disable {
 		return
 	}
-	var txt string
-	level := msg.Level
-	var b *strings.Builder
+	buf := NewBuffer()
+	defer buf.Free()
 	if c.Sprintf != nil {
-		b = c.Sprintf(msg)
+		buf.WriteString(c.Sprintf(msg).String())
+		if msg.Stack != "" {
+			buf.WriteString("\n")
+			buf.WriteString(msg.Stack)
+		}
 	} else {
-		b = msg.Sprintf()
+		encode(c.Encoder, buf, msg)
 	}
-	txt = b.String()
 
-	if c.colorful {
-		txt = level.Brush(txt)
-	}
-	if msg.Stack != "" {
-		txt = strings.Join([]string{txt, msg.Stack}, "\n")
+	out := buf
+	if _, isJSON := c.Encoder.(*JSONEncoder); c.colorful && !isJSON {
+		// 只对首行染色，堆栈等多行内容保持原样，JSON格式不染色
+		out = NewBuffer()
+		defer out.Free()
+		line, rest := buf.B, []byte(nil)
+		if i := bytes.IndexByte(line, '\n'); i >= 0 {
+			line, rest = line[:i], line[i:]
+		}
+		out.WriteString(msg.Level.Brush(string(line)))
+		out.Write(rest)
 	}
-	_, _ = fmt.Println(txt)
+	out.WriteByte('\n')
+	_, _ = os.Stdout.Write(out.B)
 }
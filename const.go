@@ -56,3 +56,8 @@ func (l Level) Brush(text string) string {
 		return strings.Join([]string{brushPrefix, color, "m", text, brushSuffix}, "")
 	}
 }
+
+// Name 去掉对齐字符后的等级名称，例如: INFO, WARN
+func (l Level) Name() string {
+	return strings.TrimSuffix(levelPrefix[l], "+")
+}
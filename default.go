@@ -1,7 +1,9 @@
 package logger
 
 import (
+	"context"
 	"fmt"
+	"time"
 )
 
 var defaultLogger *Logger
@@ -18,6 +20,11 @@ func Close() error {
 	return defaultLogger.Close()
 }
 
+// Flush 刷新默认日志器的所有输出
+func Flush(ctx context.Context) error {
+	return defaultLogger.Flush(ctx)
+}
+
 func Sprint(level Level, content string, stack ...string) {
 	defaultLogger.Sprint(level, content, stack...)
 }
@@ -51,6 +58,41 @@ func Warn(f any, v ...any) {
 	defaultLogger.Warn(f, v...)
 }
 
+func Fatalw(msg string, kv ...any) {
+	defaultLogger.Fatalw(msg, kv...)
+}
+func Panicw(msg string, kv ...any) {
+	defaultLogger.Panicw(msg, kv...)
+}
+func Errorw(msg string, kv ...any) {
+	defaultLogger.Errorw(msg, kv...)
+}
+func Alertw(msg string, kv ...any) {
+	defaultLogger.Alertw(msg, kv...)
+}
+func Debugw(msg string, kv ...any) {
+	defaultLogger.Debugw(msg, kv...)
+}
+func Tracew(msg string, kv ...any) {
+	defaultLogger.Tracew(msg, kv...)
+}
+func Infow(msg string, kv ...any) {
+	defaultLogger.Infow(msg, kv...)
+}
+func Warnw(msg string, kv ...any) {
+	defaultLogger.Warnw(msg, kv...)
+}
+
+// With 返回默认日志器绑定字段后的日志器
+func With(kv ...any) *Logger {
+	return defaultLogger.With(kv...)
+}
+
+// Named 返回默认日志器指定名称的子日志器
+func Named(name string) *Logger {
+	return defaultLogger.Named(name)
+}
+
 // SetLevel 设置日志输出等级
 func SetLevel(level Level) {
 	defaultLogger.SetLevel(level)
@@ -61,12 +103,22 @@ func SetFilePathFormatter(f filePathFormatter) {
 	defaultLogger.SetFilePathFormatter(f)
 }
 
+// SetExitFunc 设置 Fatal 退出程序的方法，默认 os.Exit
+func SetExitFunc(f func(int)) {
+	defaultLogger.SetExitFunc(f)
+}
+
+// SetExitTimeout 设置 Fatal 退出前等待输出关闭的最长时间
+func SetExitTimeout(d time.Duration) {
+	defaultLogger.SetExitTimeout(d)
+}
+
 func SetCallDepth(depth int) {
 	defaultLogger.SetCallDepth(depth)
 }
 
-func SetOutput(name string, output Output) error {
-	return defaultLogger.SetOutput(name, output)
+func SetOutput(name string, output Output, opts ...OutputOption) error {
+	return defaultLogger.SetOutput(name, output, opts...)
 }
 func DelOutput(name string) {
 	defaultLogger.DelOutput(name)
@@ -0,0 +1,280 @@
+package logger
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Encoder 将 Message 编码写入缓冲，不需要写入结尾的换行符，由各个 Output 负责分隔
+type Encoder interface {
+	Encode(buf *Buffer, msg *Message) error
+}
+
+// EncoderFunc 函数形式的 Encoder
+type EncoderFunc func(buf *Buffer, msg *Message) error
+
+func (f EncoderFunc) Encode(buf *Buffer, msg *Message) error {
+	return f(buf, msg)
+}
+
+// encode 按 Encoder 编码，未设置时使用 TextEncoder
+func encode(e Encoder, buf *Buffer, msg *Message) {
+	if e == nil {
+		e = defaultTextEncoder
+	}
+	if err := e.Encode(buf, msg); err != nil {
+		buf.Reset()
+		_ = defaultTextEncoder.Encode(buf, msg)
+	}
+}
+
+var (
+	defaultTextEncoder    = &TextEncoder{}
+	defaultContentEncoder = &ContentEncoder{}
+	defaultJSONEncoder    = &JSONEncoder{}
+)
+
+// TextEncoder 文本格式: 2006-01-02 15:04:05-0700 [INFO+] [path] name: content k=v
+type TextEncoder struct {
+	TimeLayout string // 时间格式，默认 defaultTimeLayout
+}
+
+func (e *TextEncoder) Encode(buf *Buffer, msg *Message) error {
+	layout := e.TimeLayout
+	if layout == "" {
+		layout = defaultTimeLayout
+	}
+	buf.AppendTime(msg.Time, layout)
+	buf.WriteString(" [")
+	buf.WriteString(msg.Level.String())
+	buf.WriteString("] ")
+	if msg.Path != "" {
+		buf.WriteString("[")
+		buf.WriteString(msg.Path)
+		buf.WriteString("] ")
+	}
+	msg.writeContent(buf)
+	if msg.Stack != "" {
+		buf.WriteString("\n")
+		buf.WriteString(strings.TrimRight(msg.Stack, "\n"))
+	}
+	return nil
+}
+
+// ContentEncoder 只输出日志器名称、正文、字段和堆栈，控制台默认使用
+type ContentEncoder struct{}
+
+func (e *ContentEncoder) Encode(buf *Buffer, msg *Message) error {
+	msg.writeContent(buf)
+	if msg.Stack != "" {
+		buf.WriteString("\n")
+		buf.WriteString(strings.TrimRight(msg.Stack, "\n"))
+	}
+	return nil
+}
+
+// LogfmtEncoder logfmt格式: time=... level=INFO path=... logger=... msg="..." k=v
+type LogfmtEncoder struct {
+	TimeLayout string // 时间格式，默认 time.RFC3339Nano
+}
+
+func (e *LogfmtEncoder) Encode(buf *Buffer, msg *Message) error {
+	layout := e.TimeLayout
+	if layout == "" {
+		layout = time.RFC3339Nano
+	}
+	buf.WriteString("time=")
+	buf.AppendTime(msg.Time, layout)
+	buf.WriteString(" level=")
+	buf.WriteString(msg.Level.Name())
+	if msg.Path != "" {
+		writeField(buf, "path", msg.Path)
+	}
+	if msg.Name != "" {
+		writeField(buf, "logger", msg.Name)
+	}
+	writeField(buf, "msg", msg.Content)
+	writeFields(buf, msg.Fields)
+	if msg.Stack != "" {
+		writeField(buf, "stack", msg.Stack)
+	}
+	return nil
+}
+
+// JSONEncoder JSON Lines格式，字段平铺在顶层: {"time":"...","level":"INFO","msg":"...","k":"v"}
+// 编码结果始终为单行，多行的正文和堆栈会被转义，可用于 File, Conn 和 Console
+type JSONEncoder struct {
+	TimeLayout string // 时间格式，默认 time.RFC3339Nano
+}
+
+func (e *JSONEncoder) Encode(buf *Buffer, msg *Message) error {
+	layout := e.TimeLayout
+	if layout == "" {
+		layout = time.RFC3339Nano
+	}
+	buf.WriteString(`{"time":"`)
+	buf.AppendTime(msg.Time, layout)
+	buf.WriteString(`","level":"`)
+	buf.WriteString(msg.Level.Name())
+	buf.WriteString(`"`)
+	if msg.Path != "" {
+		writeJSONField(buf, "path", msg.Path)
+	}
+	if msg.Name != "" {
+		writeJSONField(buf, "logger", msg.Name)
+	}
+	writeJSONField(buf, "msg", msg.Content)
+	for _, f := range msg.Fields {
+		key := f.Key
+		if jsonReservedKeys[key] {
+			key = "fields." + key
+		}
+		writeJSONField(buf, key, f.Value)
+	}
+	if msg.Stack != "" {
+		writeJSONField(buf, "stack", msg.Stack)
+	}
+	buf.WriteString("}")
+	return nil
+}
+
+// jsonReservedKeys JSONEncoder 使用的顶层字段，同名的字段加上 fields. 前缀，避免重复的key覆盖日志内容
+var jsonReservedKeys = map[string]bool{"time": true, "level": true, "path": true, "logger": true, "msg": true, "stack": true}
+
+func writeJSONField(buf *Buffer, key string, value any) {
+	buf.WriteString(",")
+	appendJSONString(buf, key)
+	buf.WriteString(":")
+	appendJSONValue(buf, value)
+}
+
+// appendJSONValue 按类型编码字段值，无法编码的值使用其文本形式
+func appendJSONValue(buf *Buffer, value any) {
+	switch v := value.(type) {
+	case Lazy:
+		appendJSONValue(buf, v())
+	case nil:
+		buf.WriteString("null")
+	case string:
+		appendJSONString(buf, v)
+	case bool:
+		buf.B = strconv.AppendBool(buf.B, v)
+	case int:
+		buf.AppendInt(int64(v))
+	case int8:
+		buf.AppendInt(int64(v))
+	case int16:
+		buf.AppendInt(int64(v))
+	case int32:
+		buf.AppendInt(int64(v))
+	case int64:
+		buf.AppendInt(v)
+	case uint:
+		buf.B = strconv.AppendUint(buf.B, uint64(v), 10)
+	case uint8:
+		buf.B = strconv.AppendUint(buf.B, uint64(v), 10)
+	case uint16:
+		buf.B = strconv.AppendUint(buf.B, uint64(v), 10)
+	case uint32:
+		buf.B = strconv.AppendUint(buf.B, uint64(v), 10)
+	case uint64:
+		buf.B = strconv.AppendUint(buf.B, v, 10)
+	case float32:
+		appendJSONFloat(buf, float64(v), 32)
+	case float64:
+		appendJSONFloat(buf, v, 64)
+	case time.Time:
+		buf.WriteString(`"`)
+		buf.AppendTime(v, time.RFC3339Nano)
+		buf.WriteString(`"`)
+	case time.Duration:
+		appendJSONString(buf, v.String())
+	case []byte:
+		appendJSONString(buf, string(v))
+	case json.Marshaler:
+		if b, err := v.MarshalJSON(); err == nil && json.Valid(b) {
+			buf.Write(b)
+		} else {
+			appendJSONString(buf, Field{Value: value}.String())
+		}
+	case error:
+		appendJSONString(buf, v.Error())
+	case fmt.Stringer:
+		appendJSONString(buf, v.String())
+	default:
+		if b, err := json.Marshal(v); err == nil {
+			buf.Write(b)
+		} else {
+			appendJSONString(buf, Field{Value: value}.String())
+		}
+	}
+}
+
+func appendJSONFloat(buf *Buffer, f float64, bitSize int) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		buf.WriteString(`"`)
+		buf.B = strconv.AppendFloat(buf.B, f, 'g', -1, bitSize)
+		buf.WriteString(`"`)
+		return
+	}
+	buf.B = strconv.AppendFloat(buf.B, f, 'g', -1, bitSize)
+}
+
+const hexDigits = "0123456789abcdef"
+
+// appendJSONString 写入带引号的JSON字符串，控制字符、U+2028/U+2029转义，非法UTF-8替换为U+FFFD
+func appendJSONString(buf *Buffer, s string) {
+	buf.WriteByte('"')
+	start := 0
+	for i := 0; i < len(s); {
+		if c := s[i]; c < utf8.RuneSelf {
+			if c >= 0x20 && c != '"' && c != '\\' {
+				i++
+				continue
+			}
+			buf.WriteString(s[start:i])
+			switch c {
+			case '"', '\\':
+				buf.WriteByte('\\')
+				buf.WriteByte(c)
+			case '\n':
+				buf.WriteString(`\n`)
+			case '\r':
+				buf.WriteString(`\r`)
+			case '\t':
+				buf.WriteString(`\t`)
+			default:
+				buf.WriteString(`\u00`)
+				buf.WriteByte(hexDigits[c>>4])
+				buf.WriteByte(hexDigits[c&0xf])
+			}
+			i++
+			start = i
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			buf.WriteString(s[start:i])
+			buf.WriteString(`\ufffd`)
+			i += size
+			start = i
+			continue
+		}
+		if r == '\u2028' || r == '\u2029' {
+			buf.WriteString(s[start:i])
+			buf.WriteString(`\u202`)
+			buf.WriteByte(hexDigits[r&0xf])
+			i += size
+			start = i
+			continue
+		}
+		i += size
+	}
+	buf.WriteString(s[start:])
+	buf.WriteByte('"')
+}
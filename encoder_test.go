@@ -0,0 +1,139 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func newEncoderMessage() *Message {
+	return &Message{
+		Name:    "game",
+		Path:    "game/room.go:12",
+		Time:    time.Date(2024, 5, 6, 7, 8, 9, 100, time.UTC),
+		Level:   LevelWarn,
+		Content: "slow room",
+		Fields:  []Field{Int("rid", 3), String("tag", "a b")},
+	}
+}
+
+func TestEncoders(t *testing.T) {
+	tests := []struct {
+		encoder Encoder
+		want    string
+	}{
+		{&TextEncoder{}, `2024-05-06 07:08:09+0000 [WARN+] [game/room.go:12] game: slow room rid=3 tag="a b"`},
+		{&ContentEncoder{}, `game: slow room rid=3 tag="a b"`},
+		{&LogfmtEncoder{}, `time=2024-05-06T07:08:09.0000001Z level=WARN path=game/room.go:12 logger=game msg="slow room" rid=3 tag="a b"`},
+		{&JSONEncoder{}, `{"time":"2024-05-06T07:08:09.0000001Z","level":"WARN","path":"game/room.go:12","logger":"game","msg":"slow room","rid":3,"tag":"a b"}`},
+	}
+	for _, tt := range tests {
+		buf := NewBuffer()
+		if err := tt.encoder.Encode(buf, newEncoderMessage()); err != nil {
+			t.Fatalf("%T: %v", tt.encoder, err)
+		}
+		if buf.String() != tt.want {
+			t.Errorf("%T:\n got %s\nwant %s", tt.encoder, buf.String(), tt.want)
+		}
+		buf.Free()
+	}
+}
+
+func TestJSONEncoderStack(t *testing.T) {
+	msg := newEncoderMessage()
+	msg.Stack = "goroutine 1:\n\tmain.go:1\n"
+	buf := NewBuffer()
+	defer buf.Free()
+	_ = (&JSONEncoder{}).Encode(buf, msg)
+	var r map[string]any
+	if err := json.Unmarshal(buf.B, &r); err != nil {
+		t.Fatalf("invalid json %s: %v", buf.B, err)
+	}
+	if r["stack"] != msg.Stack {
+		t.Errorf("stack = %q", r["stack"])
+	}
+}
+
+type jsonText string
+
+func (j jsonText) MarshalJSON() ([]byte, error) {
+	return []byte(j), nil
+}
+
+func TestJSONEncoderEscape(t *testing.T) {
+	msg := &Message{
+		Time:    time.Unix(0, 0).UTC(),
+		Level:   LevelError,
+		Content: "quote\" slash\\ ctrl\x01 tab\t sep  bad\xff <html>",
+		Fields: []Field{
+			Err(errors.New("line1\nline2")),
+			Duration("cost", 1500*time.Millisecond),
+			Float64("nan", math.NaN()),
+			Any("raw", jsonText(`{"a":1}`)),
+			Any("broken", jsonText(`{`)),
+			Any("list", []int{1, 2}),
+		},
+	}
+	buf := NewBuffer()
+	defer buf.Free()
+	_ = (&JSONEncoder{}).Encode(buf, msg)
+	if bytes.ContainsAny(buf.B, "\n\r") {
+		t.Fatalf("json line contains newline: %s", buf.B)
+	}
+	if !bytes.Contains(buf.B, []byte(`\u0001`)) || !bytes.Contains(buf.B, []byte(`\u2028`)) || !bytes.Contains(buf.B, []byte(`<html>`)) {
+		t.Errorf("unexpected escaping: %s", buf.B)
+	}
+	var r map[string]any
+	if err := json.Unmarshal(buf.B, &r); err != nil {
+		t.Fatalf("invalid json %s: %v", buf.B, err)
+	}
+	want := map[string]any{
+		"msg":    "quote\" slash\\ ctrl\x01 tab\t sep  bad� <html>",
+		"error":  "line1\nline2",
+		"cost":   "1.5s",
+		"nan":    "NaN",
+		"raw":    map[string]any{"a": float64(1)},
+		"broken": "{",
+		"list":   []any{float64(1), float64(2)},
+		"time":   "1970-01-01T00:00:00Z",
+		"level":  "ERROR",
+	}
+	for k, v := range want {
+		if !reflect.DeepEqual(r[k], v) {
+			t.Errorf("%s = %#v, want %#v", k, r[k], v)
+		}
+	}
+}
+
+func TestJSONEncoderReservedKeys(t *testing.T) {
+	msg := newEncoderMessage()
+	msg.Fields = Fields("msg", "override", "level", 1, "time", "now", "uid", 7)
+	buf := NewBuffer()
+	defer buf.Free()
+	_ = (&JSONEncoder{}).Encode(buf, msg)
+	if n := bytes.Count(buf.B, []byte(`"msg":`)); n != 1 {
+		t.Fatalf("duplicate msg key: %s", buf.B)
+	}
+	var r map[string]any
+	if err := json.Unmarshal(buf.B, &r); err != nil {
+		t.Fatalf("invalid json %s: %v", buf.B, err)
+	}
+	want := map[string]any{
+		"msg":          "slow room",
+		"level":        "WARN",
+		"time":         "2024-05-06T07:08:09.0000001Z",
+		"fields.msg":   "override",
+		"fields.level": float64(1),
+		"fields.time":  "now",
+		"uid":          float64(7),
+	}
+	for k, v := range want {
+		if !reflect.DeepEqual(r[k], v) {
+			t.Errorf("%s = %#v, want %#v", k, r[k], v)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package logger
+
+import (
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const badKey = "!BADKEY" // kv参数无法配对时使用的键名
+
+// Field 结构化日志字段
+type Field struct {
+	Key   string
+	Value any
+}
+
+func Any(key string, value any) Field {
+	return Field{Key: key, Value: value}
+}
+
+func String(key string, value string) Field {
+	return Field{Key: key, Value: value}
+}
+
+func Int(key string, value int) Field {
+	return Field{Key: key, Value: value}
+}
+
+func Int64(key string, value int64) Field {
+	return Field{Key: key, Value: value}
+}
+
+func Uint64(key string, value uint64) Field {
+	return Field{Key: key, Value: value}
+}
+
+func Float64(key string, value float64) Field {
+	return Field{Key: key, Value: value}
+}
+
+func Bool(key string, value bool) Field {
+	return Field{Key: key, Value: value}
+}
+
+func Duration(key string, value time.Duration) Field {
+	return Field{Key: key, Value: value}
+}
+
+func Time(key string, value time.Time) Field {
+	return Field{Key: key, Value: value}
+}
+
+// Err 错误字段，键名固定为 error
+func Err(err error) Field {
+	return Field{Key: "error", Value: err}
+}
+
+// Fields 将 Field 或者 key,value 交替排列的参数转换成字段列表
+// 例如: Fields("uid", 1001, logger.Duration("cost", d))
+func Fields(kv ...any) []Field {
+	if len(kv) == 0 {
+		return nil
+	}
+	r := make([]Field, 0, len(kv))
+	for i := 0; i < len(kv); i++ {
+		switch v := kv[i].(type) {
+		case Field:
+			r = append(r, v)
+		case []Field:
+			r = append(r, v...)
+		case string:
+			if i+1 < len(kv) {
+				r = append(r, Field{Key: v, Value: kv[i+1]})
+				i++
+			} else {
+				r = append(r, Field{Key: badKey, Value: v})
+			}
+		default:
+			r = append(r, Field{Key: badKey, Value: v})
+		}
+	}
+	return r
+}
+
+// Lazy 延迟求值的参数，只有日志确实需要输出时才会调用
+// 例如: Debug("state:%v", logger.Lazy(func() any { return room.Dump() }))
+type Lazy func() any
+
+func (f Lazy) Format(s fmt.State, verb rune) {
+	_, _ = fmt.Fprintf(s, fmt.FormatString(s, verb), f())
+}
+
+func (f Lazy) String() string {
+	return Field{Value: f()}.String()
+}
+
+func (f Lazy) LogValue() slog.Value {
+	return slog.AnyValue(f())
+}
+
+// String 返回字段值的文本形式
+func (f Field) String() string {
+	switch v := f.Value.(type) {
+	case Lazy:
+		return v.String()
+	case nil:
+		return "<nil>"
+	case string:
+		return v
+	case error:
+		return v.Error()
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	case fmt.Stringer:
+		return v.String()
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// writeFields 以 key=value 的形式输出字段，值中包含空白或引号时加引号
+func writeFields(buf *Buffer, fields []Field) {
+	for _, f := range fields {
+		writeField(buf, f.Key, f.String())
+	}
+}
+
+func writeField(buf *Buffer, key, value string) {
+	buf.WriteString(" ")
+	buf.WriteString(key)
+	buf.WriteString("=")
+	if value == "" || strings.ContainsAny(value, " \t\r\n\"=") {
+		buf.B = strconv.AppendQuote(buf.B, value)
+	} else {
+		buf.WriteString(value)
+	}
+}
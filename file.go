@@ -2,12 +2,19 @@ package logger
 
 import (
 	"bufio"
+	"compress/gzip"
+	"context"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -23,9 +30,14 @@ type fileSystem struct {
 	bufferedWriter *bufio.Writer // 缓冲写入器
 }
 
+const (
+	compressExt     = ".gz"
+	compressTempExt = ".gz.tmp"
+)
+
 type fileNameFormatter func() (name, backup string, expire int64)
 
-// FileNameFormatterDefault 默认日志文件,每日一份
+// FileNameFormatterDefault 默认日志文件,每月备份一次
 func FileNameFormatterDefault() (name, backup string, expire int64) {
 	t := time.Now()
 	backup = t.Format("200601")
@@ -43,14 +55,18 @@ func NewFile(path string, cap ...int) *File {
 		index: 1,
 	}
 	if len(cap) > 0 {
-		f.writer = make(chan *strings.Builder, cap[0])
+		f.writer = make(chan *Buffer, cap[0])
 	} else {
-		f.writer = make(chan *strings.Builder, 1000)
+		f.writer = make(chan *Buffer, 1000)
 	}
-	f.bufferFlushInterval = time.Second //默认一秒刷新一次
+	f.flush = make(chan chan error)
+	f.reopen = make(chan chan error)
+	f.done = make(chan struct{})
+	f.bufferFlushInterval.Store(int64(time.Second)) //默认一秒刷新一次
 	f.fileNameFormatter = FileNameFormatterDefault
 	f.wg.Add(1)
 	go f.process()
+	registerFile(f)
 	return f
 }
 
@@ -60,24 +76,86 @@ type File struct {
 	path                string                          //日志目录
 	limit               int64                           //文件大小(byte),0：不需要按容量切分
 	index               int                             //备份文件后缀
-	Sprintf             func(*Message) *strings.Builder //格式化message
-	writer              chan *strings.Builder           //写通道
+	Encoder             Encoder                         //编码器,默认 TextEncoder
+	Sprintf             func(*Message) *strings.Builder //格式化message, Deprecated: 使用 Encoder
+	writer              chan *Buffer                    //写通道
+	flush               chan chan error                 //刷新请求,由process处理后返回结果
+	reopen              chan chan error                 //重新打开文件请求,由process处理后返回结果
+	done                chan struct{}                   //process退出后关闭
 	fileNameFormatter   fileNameFormatter               //日志名规则
-	bufferFlushInterval time.Duration                   //缓冲区时间间隔
+	filePattern         string                          //带时间的文件名模式,用于清理和压缩过期的文件
+	symlink             string                          //指向当前日志文件的符号链接,为空时不创建
+	bufferFlushInterval atomic.Int64                    //缓冲区时间间隔,运行时可以修改
+	maxBackups          int                             //最多保留的备份数量,0：不限制
+	maxAge              time.Duration                   //备份最长保留时间,0：不限制
+	maxTotalSize        int64                           //日志文件总大小(byte),0：不限制
+	compress            bool                            //是否使用gzip压缩备份
+	compressor          chan string                     //待压缩的备份,由compressFiles协程处理
 }
 
-// SetFileSize 设置文件大小(M)，默认无限制
+// SetFileSize 设置文件大小(M)，默认无限制，文件名没有备份名后缀时无效(例如使用文件名模式)
 // 注意：该方法只应在初始化时调用
 func (f *File) SetFileSize(n int64) {
 	// limit字段仅在初始化时设置，无需并发保护
 	f.limit = n * 1024 * 1024
 }
 
-// SetFileName 设置日志文件名,  前缀(string) 或者 fileNameFormatter
+// SetMaxBackups 设置最多保留的备份数量，默认不限制
+// 注意：该方法只应在初始化时调用
+func (f *File) SetMaxBackups(n int) {
+	f.maxBackups = n
+}
+
+// SetMaxAge 设置备份最长保留时间，按文件修改时间计算，默认不限制
 // 注意：该方法只应在初始化时调用
-func (f *File) SetFileName(fileNameFormatterFunc fileNameFormatter) {
+func (f *File) SetMaxAge(d time.Duration) {
+	f.maxAge = d
+}
+
+// SetMaxTotalSize 设置当前日志和所有备份的总大小(M)，超出时从最旧的备份开始删除，默认不限制
+// 注意：该方法只应在初始化时调用
+func (f *File) SetMaxTotalSize(n int64) {
+	f.maxTotalSize = n * 1024 * 1024
+}
+
+// SetCompress 设置是否在后台使用gzip压缩备份，压缩后的备份为 name.backup.NNNN.ext.gz
+// 由于标准库没有zstd，目前只支持gzip
+// 注意：该方法只应在初始化时调用
+func (f *File) SetCompress(compress bool) {
+	f.compress = compress
+}
+
+// SetSymlink 设置指向当前日志文件的符号链接，例如 current.log，位于日志目录中，每次切分后自动指向新文件
+// 注意：该方法只应在初始化时调用
+func (f *File) SetSymlink(name string) {
+	f.symlink = name
+}
+
+// SetFileName 设置日志文件名, 文件名(string), FileNamePattern 或者 fileNameFormatter
+// 文件名包含 %Y 等时间格式时参考 FileNamePattern，例如 app-%Y%m%d.log，否则作为文件名并按月备份
+// 注意：该方法只应在初始化时调用
+func (f *File) SetFileName(name any) {
 	// fileName字段仅在初始化时设置，无需并发保护
-	f.fileNameFormatter = fileNameFormatterFunc
+	switch v := name.(type) {
+	case string:
+		if strings.Contains(v, "%") {
+			f.SetFileName(FileNamePattern(v))
+		} else {
+			f.fileNameFormatter = RotateMonthly(v)
+			f.filePattern = ""
+		}
+	case fileNamePattern:
+		f.fileNameFormatter = v.formatter()
+		f.filePattern = string(v)
+	case fileNameFormatter:
+		f.fileNameFormatter = v
+		f.filePattern = ""
+	case func() (string, string, int64):
+		f.fileNameFormatter = v
+		f.filePattern = ""
+	default:
+		fmt.Printf("logger SetFileName unsupported type:%T", name)
+	}
 }
 
 // SetFlushInterval 设置缓冲区刷新间隔
@@ -86,18 +164,17 @@ func (f *File) SetFlushInterval(interval time.Duration) {
 	if interval <= 0 {
 		return // 不允许设置非正的刷新间隔
 	}
-	f.bufferFlushInterval = interval
+	f.bufferFlushInterval.Store(int64(interval))
 }
 
 func (f *File) Write(msg *Message) {
-	var b *strings.Builder
-
+	b := NewBuffer()
 	if f.Sprintf != nil {
-		b = f.Sprintf(msg)
+		b.WriteString(f.Sprintf(msg).String())
 	} else {
-		b = msg.Sprintf()
+		encode(f.Encoder, b, msg)
 	}
-	b.WriteString("\n")
+	b.WriteByte('\n')
 
 	// 阻塞模式写入，确保所有日志都能被处理
 	f.writer <- b
@@ -105,6 +182,7 @@ func (f *File) Write(msg *Message) {
 
 // Close 优雅关闭日志文件
 func (f *File) Close() error {
+	unregisterFile(f)
 	// 关闭writer通道发送关闭信号
 	// 注意：不再需要单独的close通道，writer通道的关闭信号已足够
 	close(f.writer)
@@ -117,8 +195,38 @@ func (f *File) Close() error {
 	return nil
 }
 
+// Flush 写入通道中已有的日志并将缓冲区刷新到磁盘，不会关闭文件
+func (f *File) Flush(ctx context.Context) error {
+	result := make(chan error, 1)
+	select {
+	case f.flush <- result:
+	case <-f.done:
+		return nil // process已退出，退出时已经刷新
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case err := <-result:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Reopen 刷新缓冲区后关闭并重新打开当前日志文件，不会备份，用于配合外部 logrotate 等工具
+func (f *File) Reopen() error {
+	result := make(chan error, 1)
+	select {
+	case f.reopen <- result:
+	case <-f.done:
+		return nil
+	}
+	return <-result
+}
+
 func (f *File) process() {
 	defer f.wg.Done()
+	defer close(f.done)
 	defer func() {
 		// 确保在退出前刷新缓冲区并释放资源
 		if f.fs != nil && f.fs.bufferedWriter != nil {
@@ -126,10 +234,14 @@ func (f *File) process() {
 			f.fs.bufferedWriter = nil
 			f.fs.file = nil
 		}
+		// 等待压缩协程处理完剩余的备份
+		if f.compressor != nil {
+			close(f.compressor)
+		}
 	}()
 
 	// 创建定时器并确保在函数退出时停止
-	timer := time.NewTimer(f.bufferFlushInterval)
+	timer := time.NewTimer(time.Duration(f.bufferFlushInterval.Load()))
 	defer timer.Stop()
 
 	// 持续处理writer通道中的消息，直到通道关闭
@@ -140,41 +252,115 @@ func (f *File) process() {
 				return
 			}
 			f.writeFile(b)
+		case result := <-f.flush:
+			result <- f.flushFile()
+		case result := <-f.reopen:
+			result <- f.reopenFile()
 		case <-timer.C:
+			if f.fileChanged() {
+				if err := f.reopenFile(); err != nil {
+					fmt.Printf("logger reopen file error:%v", err)
+				}
+			}
 			if f.mayNeedBackup() {
 				f.createFile()
 			} else if f.fs != nil && f.fs.bufferedWriter != nil {
 				_ = f.fs.bufferedWriter.Flush()
 			}
-			timer.Reset(f.bufferFlushInterval)
+			timer.Reset(time.Duration(f.bufferFlushInterval.Load()))
 		}
 	}
 }
 
-func (f *File) writeFile(b *strings.Builder) {
+// flushFile 写入通道中排队的日志后刷新缓冲区并同步到磁盘
+func (f *File) flushFile() error {
+	for n := len(f.writer); n > 0; n-- {
+		b, ok := <-f.writer
+		if !ok {
+			break
+		}
+		f.writeFile(b)
+	}
+	if f.fs == nil || f.fs.bufferedWriter == nil {
+		return nil
+	}
+	if err := f.fs.bufferedWriter.Flush(); err != nil {
+		return err
+	}
+	return f.fs.file.Sync()
+}
+
+func (f *File) writeFile(b *Buffer) {
+	defer b.Free()
 	defer func() {
 		if e := recover(); e != nil {
 			fmt.Printf("logger write file recover error:%v", e)
 		}
 	}()
 
+	// 首次写入时创建文件，避免定时器触发前的日志丢失
+	if f.fs == nil {
+		f.createFile()
+	}
 	if f.fs == nil || f.fs.bufferedWriter == nil {
 		return
 	}
 
 	// 直接写入缓冲写入器，避免不必要的转换
-	if n, err := f.fs.bufferedWriter.WriteString(b.String()); err != nil && n > 0 {
+	if n, err := f.fs.bufferedWriter.Write(b.B); err != nil && n > 0 {
 		fmt.Printf("logger write file WriteString error:%v", err)
 	} else if n > 0 {
 		f.fs.size += int64(n)
 
 		// 定期刷新缓冲区，但不要每次都刷新
-		if f.fs.bufferedWriter.Available() < len(b.String())*2 {
+		if f.fs.bufferedWriter.Available() < b.Len()*2 {
 			_ = f.fs.bufferedWriter.Flush()
 		}
 	}
 }
 
+// fileChanged 文件被外部工具移走、替换或者截断
+func (f *File) fileChanged() bool {
+	if f.fs == nil || f.fs.file == nil {
+		return false
+	}
+	fi, err := os.Stat(f.fs.file.Name())
+	if err != nil {
+		return os.IsNotExist(err)
+	}
+	cur, err := f.fs.file.Stat()
+	if err != nil {
+		return false
+	}
+	// size 包含缓冲区中还没有写入的内容
+	return !os.SameFile(fi, cur) || fi.Size() < f.fs.size-int64(f.fs.bufferedWriter.Buffered())
+}
+
+// reopenFile 刷新缓冲区后重新打开当前文件，文件已经不存在时重新创建
+// 先打开新文件再关闭旧文件，打开失败时继续使用旧文件，下一次定时器触发时重试
+func (f *File) reopenFile() error {
+	if f.fs == nil || f.fs.file == nil {
+		return nil
+	}
+	err := f.fs.bufferedWriter.Flush()
+	fd, e := os.OpenFile(f.fs.file.Name(), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0777)
+	if e != nil {
+		return e
+	}
+	fi, e := fd.Stat()
+	if e != nil {
+		_ = fd.Close()
+		return e
+	}
+	if e = f.fs.file.Close(); err == nil {
+		err = e
+	}
+	f.fs.file = fd
+	f.fs.size = fi.Size()
+	f.fs.bufferedWriter.Reset(fd)
+	return err
+}
+
 // mayNeedBackup 是否需要开始备份
 func (f *File) mayNeedBackup() bool {
 	// 所有字段访问都在同一个goroutine中，无需锁保护
@@ -184,7 +370,8 @@ func (f *File) mayNeedBackup() bool {
 	if f.fs.file == nil {
 		return true
 	}
-	if f.limit > 0 && f.fs.size >= f.limit {
+	// 没有备份名后缀时无法按大小切分，例如使用文件名模式
+	if f.limit > 0 && f.fs.backup != "" && f.fs.size >= f.limit {
 		return true
 	}
 	if f.fs.expire > 0 && f.fs.expire < time.Now().Unix() {
@@ -219,7 +406,8 @@ func (f *File) createFile() {
 	}
 
 	// 备份旧文件
-	f.backupFile(oldFS)
+	first := oldFS == nil
+	f.backupFile(oldFS, filepath.Join(path, name))
 	oldFS = nil //备份后文件系统已经被释放不可以重新使用
 
 	var perm int64
@@ -251,11 +439,18 @@ func (f *File) createFile() {
 
 	// 替换旧的文件系统对象
 	f.fs = newFS
+	f.linkFile()
+	// 先清理再恢复压缩，避免压缩即将被删除的备份
+	f.cleanup()
+	if first {
+		f.recoverBackups()
+	}
 }
 
 // backupFile 使用静默方式，如果失败新的文件系统也只会继续使用当前文件
-func (f *File) backupFile(fs *fileSystem) {
-	if fs == nil || fs.backup == "" {
+// next 为新的日志文件，没有备份名后缀时只关闭旧文件，使用文件名模式时旧文件交给压缩协程
+func (f *File) backupFile(fs *fileSystem, next string) {
+	if fs == nil {
 		return
 	}
 	var err error
@@ -273,6 +468,12 @@ func (f *File) backupFile(fs *fileSystem) {
 	if err = fs.file.Close(); err != nil {
 		return
 	}
+	if fs.backup == "" {
+		if f.filePattern != "" && name != next {
+			f.compressBackup(name)
+		}
+		return
+	}
 
 	// 备份操作不需要修改f.fs，因为我们只在createFile中替换它
 	ext := filepath.Ext(name)
@@ -284,14 +485,213 @@ func (f *File) backupFile(fs *fileSystem) {
 		s := strconv.Itoa(10000 + i)
 		s = strings.TrimPrefix(s, "1")
 		filename := filepath.Join(path, fmt.Sprintf("%s.%s%s", base, s, ext))
-		if f.fileExists(filename) {
+		if f.fileExists(filename) || f.fileExists(filename+compressExt) {
 			continue
 		}
 		if err = os.Rename(name, filename); err == nil {
 			f.index = i
+			f.compressBackup(filename)
+			break
+		}
+	}
+}
+
+// linkFile 先创建临时链接再重命名，原子地将符号链接指向当前文件
+func (f *File) linkFile() {
+	if f.symlink == "" || f.fs == nil || f.fs.file == nil {
+		return
+	}
+	name := f.fs.file.Name()
+	link := filepath.Join(filepath.Dir(name), f.symlink)
+	if link == name {
+		return
+	}
+	tmp := link + ".tmp"
+	_ = os.Remove(tmp)
+	// 链接使用相对路径，日志目录整体移动后仍然有效
+	err := os.Symlink(filepath.Base(name), tmp)
+	if err == nil {
+		if err = os.Rename(tmp, link); err != nil {
+			_ = os.Remove(tmp)
+		}
+	}
+	if err != nil {
+		fmt.Printf("logger symlink error:%v", err)
+	}
+}
+
+// fileBackup 备份文件信息
+type fileBackup struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+// backups 查找 name 对应的备份文件，只匹配 name.backup.NNNN.ext 形式的文件以及压缩后的文件和压缩临时文件，按修改时间从旧到新排序
+// backup 只匹配内置规则使用的数字时间，避免误删同目录下其他服务的文件，使用文件名模式时匹配模式生成的其他文件
+func (f *File) backups(name string) ([]fileBackup, error) {
+	dir := filepath.Dir(name)
+	ext := filepath.Ext(name)
+	base := strings.TrimSuffix(filepath.Base(name), ext)
+	expr := regexp.QuoteMeta(base) + `\.\d+\.\d{4,}` + regexp.QuoteMeta(ext)
+	if f.filePattern != "" {
+		_, _, expr = parseFilePattern(f.filePattern)
+	}
+	re, err := regexp.Compile(`^` + expr + `(\.gz(\.tmp)?)?$`)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var backups []fileBackup
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() || entry.Name() == filepath.Base(name) || !re.MatchString(entry.Name()) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		backups = append(backups, fileBackup{path: filepath.Join(dir, entry.Name()), size: info.Size(), modTime: info.ModTime()})
+	}
+	sort.Slice(backups, func(i, j int) bool {
+		if !backups[i].modTime.Equal(backups[j].modTime) {
+			return backups[i].modTime.Before(backups[j].modTime)
+		}
+		return backups[i].path < backups[j].path
+	})
+	return backups, nil
+}
+
+// cleanup 按数量、时间和总大小删除最旧的备份，在 createFile 之后执行
+func (f *File) cleanup() {
+	if f.maxBackups <= 0 && f.maxAge <= 0 && f.maxTotalSize <= 0 || f.fs == nil || f.fs.file == nil {
+		return
+	}
+	backups, err := f.backups(f.fs.file.Name())
+	if err != nil {
+		fmt.Printf("logger cleanup backups error:%v", err)
+		return
+	}
+	// 压缩临时文件由压缩协程处理，不参与清理
+	backups = slices.DeleteFunc(backups, func(b fileBackup) bool { return strings.HasSuffix(b.path, compressTempExt) })
+	total := f.fs.size
+	for _, b := range backups {
+		total += b.size
+	}
+	now := time.Now()
+	for i, b := range backups {
+		// 备份按从旧到新排列，第一个不需要删除的备份之后的都需要保留
+		if !(f.maxBackups > 0 && len(backups)-i > f.maxBackups ||
+			f.maxAge > 0 && now.Sub(b.modTime) > f.maxAge ||
+			f.maxTotalSize > 0 && total > f.maxTotalSize) {
 			break
 		}
+		if err = os.Remove(b.path); err != nil {
+			fmt.Printf("logger remove backup error:%v", err)
+			continue
+		}
+		total -= b.size
+	}
+}
+
+// compressBackup 将备份交给压缩协程，不会阻塞 process，队列满时备份保持不压缩，下次启动时恢复
+func (f *File) compressBackup(path string) {
+	if !f.compress {
+		return
+	}
+	if f.compressor == nil {
+		f.compressor = make(chan string, 100)
+		f.wg.Add(1)
+		go f.compressFiles()
+	}
+	select {
+	case f.compressor <- path:
+	default:
+		fmt.Printf("logger compress queue full:%v", path)
+	}
+}
+
+// recoverBackups 启动时处理上次退出时未完成的压缩，删除临时文件并重新压缩未压缩的备份
+func (f *File) recoverBackups() {
+	if !f.compress || f.fs == nil || f.fs.file == nil {
+		return
+	}
+	backups, err := f.backups(f.fs.file.Name())
+	if err != nil {
+		fmt.Printf("logger recover backups error:%v", err)
+		return
+	}
+	for _, b := range backups {
+		switch {
+		case strings.HasSuffix(b.path, compressTempExt):
+			_ = os.Remove(b.path)
+		case !strings.HasSuffix(b.path, compressExt):
+			f.compressBackup(b.path)
+		}
+	}
+}
+
+func (f *File) compressFiles() {
+	defer f.wg.Done()
+	for path := range f.compressor {
+		if err := compressFile(path); err != nil {
+			fmt.Printf("logger compress backup error:%v", err)
+		}
+	}
+}
+
+// compressFile 压缩到临时文件后重命名，保留原文件的修改时间，最后删除原文件
+func compressFile(path string) (err error) {
+	dst := path + compressExt
+	// 上次压缩已经完成但是没有删除原文件
+	if _, err = os.Stat(dst); err == nil {
+		return os.Remove(path)
+	}
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	info, err := src.Stat()
+	if err != nil {
+		return err
+	}
+	tmp := path + compressTempExt
+	out, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode())
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = out.Close()
+			_ = os.Remove(tmp)
+		}
+	}()
+	w := gzip.NewWriter(out)
+	if _, err = io.Copy(w, src); err != nil {
+		return err
+	}
+	if err = w.Close(); err != nil {
+		return err
+	}
+	if err = out.Sync(); err != nil {
+		return err
+	}
+	if err = out.Close(); err != nil {
+		return err
+	}
+	// 压缩期间备份已经被清理
+	if _, err = os.Stat(path); err != nil {
+		return err
+	}
+	if err = os.Rename(tmp, dst); err != nil {
+		return err
 	}
+	_ = os.Chtimes(dst, info.ModTime(), info.ModTime())
+	return os.Remove(path)
 }
 
 func (f *File) fileExists(file string) bool {
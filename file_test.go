@@ -1,9 +1,17 @@
 package logger
 
 import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
 	"sync"
+	"syscall"
 	"testing"
 	"time"
 )
@@ -90,3 +98,347 @@ func TestFileWritePerformance(t *testing.T) {
 		})
 	}
 }
+
+// TestFileJSONLines 测试File使用JSONEncoder时每行一个JSON对象
+func TestFileJSONLines(t *testing.T) {
+	dir := t.TempDir()
+	f := NewFile(dir)
+	f.Encoder = &JSONEncoder{}
+	f.Write(&Message{Level: LevelError, Time: time.Now(), Content: "multi\nline", Stack: "goroutine 1\n\tmain.go:1\n"})
+	f.Write(&Message{Level: LevelInfo, Time: time.Now(), Content: "second", Fields: []Field{Int("uid", 1)}})
+	_ = f.Close()
+
+	data, err := os.ReadFile(filepath.Join(dir, "log.log"))
+	if err != nil {
+		t.Fatalf("read log file: %v", err)
+	}
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("want 2 lines, got %d: %q", len(lines), data)
+	}
+	for _, line := range lines {
+		var r map[string]any
+		if err = json.Unmarshal([]byte(line), &r); err != nil {
+			t.Errorf("invalid json line %q: %v", line, err)
+		}
+	}
+}
+
+// TestFileFlush 测试Flush后日志已经写入磁盘，并且File可以继续使用
+func TestFileFlush(t *testing.T) {
+	dir := t.TempDir()
+	log := New()
+	log.SetLevel(LevelDebug)
+	f := NewFile(dir)
+	f.SetFlushInterval(time.Hour)
+	_ = log.SetOutput("file", f)
+	defer log.Close()
+
+	name := filepath.Join(dir, "log.log")
+	for i := 1; i <= 2; i++ {
+		for j := 0; j < 100; j++ {
+			log.Info("round %d line %d", i, j)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		err := log.Flush(ctx)
+		cancel()
+		if err != nil {
+			t.Fatalf("flush error: %v", err)
+		}
+		data, _ := os.ReadFile(name)
+		if n := strings.Count(string(data), "\n"); n != i*100 {
+			t.Fatalf("round %d: want %d lines, got %d", i, i*100, n)
+		}
+	}
+	_ = log.Close()
+	if err := f.Flush(context.Background()); err != nil {
+		t.Errorf("flush after close: %v", err)
+	}
+}
+
+// waitFile 等待条件成立，File 的切分由定时器触发
+func waitFile(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("wait file timeout")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// TestFileRetention 测试切分后按数量和时间删除备份，并且不会删除其他文件
+func TestFileRetention(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	files := map[string]time.Duration{
+		"log.202601.0001.log":        48 * time.Hour,
+		"log.202601.0002.log":        24 * time.Hour,
+		"log.202601.0003.log":        time.Hour,
+		"other.202601.0001.log":      72 * time.Hour,
+		"log.log.bak":                72 * time.Hour,
+		"log.202601.x.log":           72 * time.Hour,
+		"log.other-service.0001.log": 72 * time.Hour,
+	}
+	for name, age := range files {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte("old\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, now.Add(-age), now.Add(-age)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	f := NewFile(dir)
+	f.limit = 1
+	f.SetMaxBackups(2)
+	f.SetMaxAge(36 * time.Hour)
+	f.Write(&Message{Level: LevelInfo, Time: now, Content: "rotate"})
+	rotated := filepath.Join(dir, fmt.Sprintf("log.%s.0002.log", now.Format("200601")))
+	waitFile(t, func() bool { return f.fileExists(rotated) })
+	_ = f.Close()
+
+	for name := range files {
+		exists := f.fileExists(filepath.Join(dir, name))
+		if want := name != "log.202601.0001.log" && name != "log.202601.0002.log"; exists != want {
+			t.Errorf("%s exists=%v, want %v", name, exists, want)
+		}
+	}
+}
+
+// TestFileRetentionTotalSize 测试总大小超出时从最旧的备份开始删除
+func TestFileRetentionTotalSize(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	for i := 1; i <= 3; i++ {
+		path := filepath.Join(dir, fmt.Sprintf("log.202601.000%d.log", i))
+		if err := os.WriteFile(path, make([]byte, 100), 0644); err != nil {
+			t.Fatal(err)
+		}
+		mod := now.Add(-time.Duration(10-i) * time.Hour)
+		if err := os.Chtimes(path, mod, mod); err != nil {
+			t.Fatal(err)
+		}
+	}
+	f := NewFile(dir)
+	f.maxTotalSize = 250
+	f.Write(&Message{Level: LevelInfo, Time: now, Content: "size"})
+	_ = f.Close()
+
+	for i, want := range []bool{false, true, true} {
+		if exists := f.fileExists(filepath.Join(dir, fmt.Sprintf("log.202601.000%d.log", i+1))); exists != want {
+			t.Errorf("backup %d exists=%v, want %v", i+1, exists, want)
+		}
+	}
+}
+
+// TestFileCompress 测试切分后在后台压缩备份，备份序号跳过已经压缩的文件，并恢复上次未完成的压缩
+func TestFileCompress(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	month := now.Format("200601")
+	leftovers := map[string]string{
+		"log.202601.0005.log":                    "uncompressed\n",
+		"log.202601.0006.log.gz.tmp":             "partial",
+		fmt.Sprintf("log.%s.0002.log.gz", month): "",
+	}
+	for name, data := range leftovers {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	f := NewFile(dir)
+	f.limit = 1
+	f.SetCompress(true)
+	f.Write(&Message{Level: LevelInfo, Time: now, Content: "compress me"})
+	rotated := filepath.Join(dir, fmt.Sprintf("log.%s.0003.log", month))
+	waitFile(t, func() bool { return f.fileExists(rotated + ".gz") })
+	_ = f.Close()
+
+	for name, want := range map[string]bool{
+		rotated: false,
+		filepath.Join(dir, "log.202601.0005.log"):        false,
+		filepath.Join(dir, "log.202601.0005.log.gz"):     true,
+		filepath.Join(dir, "log.202601.0006.log.gz.tmp"): false,
+	} {
+		if exists := f.fileExists(name); exists != want {
+			t.Errorf("%s exists=%v, want %v", name, exists, want)
+		}
+	}
+	for name, want := range map[string]string{rotated + ".gz": "compress me", filepath.Join(dir, "log.202601.0005.log.gz"): "uncompressed"} {
+		fd, err := os.Open(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		zr, err := gzip.NewReader(fd)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		data, _ := io.ReadAll(zr)
+		_ = fd.Close()
+		if !strings.Contains(string(data), want) {
+			t.Errorf("%s: unexpected content %q", name, data)
+		}
+	}
+}
+
+// TestFileSymlink 测试符号链接始终指向当前日志文件
+func TestFileSymlink(t *testing.T) {
+	dir := t.TempDir()
+	f := NewFile(dir)
+	n := 0
+	f.SetFileName(func() (name, backup string, expire int64) {
+		n++
+		if n == 1 {
+			// 第一个文件立即过期，下一次定时器触发时切分
+			return "app-1.log", "", time.Now().Unix() - 1
+		}
+		return fmt.Sprintf("app-%d.log", n), "", 0
+	})
+	f.SetSymlink("current.log")
+	link := filepath.Join(dir, "current.log")
+	f.Write(&Message{Level: LevelInfo, Time: time.Now(), Content: "first"})
+	waitFile(t, func() bool {
+		target, _ := os.Readlink(link)
+		return target == "app-2.log"
+	})
+	f.Write(&Message{Level: LevelInfo, Time: time.Now(), Content: "second"})
+	_ = f.Close()
+
+	data, err := os.ReadFile(link)
+	if err != nil || !strings.Contains(string(data), "second") || strings.Contains(string(data), "first") {
+		t.Fatalf("read through symlink %q: %v", data, err)
+	}
+	if data, _ = os.ReadFile(filepath.Join(dir, "app-1.log")); !strings.Contains(string(data), "first") {
+		t.Fatalf("old file %q", data)
+	}
+	if f.fileExists(link + ".tmp") {
+		t.Fatalf("temporary symlink left behind")
+	}
+}
+
+// TestFileReopen 测试外部工具移走日志文件后 Reopen 和定时器自动重新打开文件
+func TestFileReopen(t *testing.T) {
+	dir := t.TempDir()
+	f := NewFile(dir)
+	defer f.Close()
+	name := filepath.Join(dir, "log.log")
+	write := func(content string) {
+		f.Write(&Message{Level: LevelInfo, Time: time.Now(), Content: content})
+		if err := f.Flush(context.Background()); err != nil {
+			t.Fatalf("flush: %v", err)
+		}
+	}
+	read := func(name string) string {
+		data, _ := os.ReadFile(name)
+		return string(data)
+	}
+
+	// logrotate create 模式: 移走文件后通知重新打开
+	write("first")
+	if err := os.Rename(name, name+".1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Reopen(); err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	write("second")
+	if s := read(name + ".1"); !strings.Contains(s, "first") || strings.Contains(s, "second") {
+		t.Fatalf("rotated file %q", s)
+	}
+	if s := read(name); !strings.Contains(s, "second") || strings.Contains(s, "first") {
+		t.Fatalf("reopened file %q", s)
+	}
+
+	// 没有通知时由定时器发现文件被移走
+	if err := os.Rename(name, name+".2"); err != nil {
+		t.Fatal(err)
+	}
+	waitFile(t, func() bool { return f.fileExists(name) })
+	write("third")
+	if s := read(name); !strings.Contains(s, "third") || strings.Contains(s, "second") {
+		t.Fatalf("recreated file %q", s)
+	}
+
+	// logrotate copytruncate 模式: 截断后继续从头写入
+	if err := os.Truncate(name, 0); err != nil {
+		t.Fatal(err)
+	}
+	write("fourth")
+	if s := read(name); strings.Contains(s, "\x00") || !strings.Contains(s, "fourth") || strings.Contains(s, "third") {
+		t.Fatalf("truncated file %q", s)
+	}
+}
+
+// TestReopenOnSignal 测试收到信号时重新打开所有 File
+func TestReopenOnSignal(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("signal not supported")
+	}
+	dir := t.TempDir()
+	f := NewFile(dir)
+	defer f.Close()
+	name := filepath.Join(dir, "log.log")
+	f.Write(&Message{Level: LevelInfo, Time: time.Now(), Content: "before"})
+	_ = f.Flush(context.Background())
+	if err := os.Rename(name, name+".1"); err != nil {
+		t.Fatal(err)
+	}
+
+	stop := ReopenOnSignal(syscall.SIGHUP)
+	defer stop()
+	p, _ := os.FindProcess(os.Getpid())
+	if err := p.Signal(syscall.SIGHUP); err != nil {
+		t.Fatalf("signal: %v", err)
+	}
+	// 信号处理之前定时器也可能已经重新创建文件
+	waitFile(t, func() bool { return f.fileExists(name) })
+	f.Write(&Message{Level: LevelInfo, Time: time.Now(), Content: "after"})
+	_ = f.Flush(context.Background())
+	if data, _ := os.ReadFile(name); !strings.Contains(string(data), "after") {
+		t.Fatalf("reopened file %q", data)
+	}
+}
+
+// TestFileReopenFailure 测试重新打开失败时继续使用旧文件，恢复后重新打开
+func TestFileReopenFailure(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "logs")
+	if err := os.Mkdir(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	f := NewFile(dir)
+	defer f.Close()
+	write := func(content string) {
+		f.Write(&Message{Level: LevelInfo, Time: time.Now(), Content: content})
+		if err := f.Flush(context.Background()); err != nil {
+			t.Fatalf("flush: %v", err)
+		}
+	}
+	write("first")
+	moved := dir + ".moved"
+	if err := os.Rename(dir, moved); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Reopen(); err == nil {
+		t.Fatalf("reopen should fail without directory")
+	}
+	// 定时器重试失败也不会影响写入
+	time.Sleep(1200 * time.Millisecond)
+	write("second")
+	if data, _ := os.ReadFile(filepath.Join(moved, "log.log")); !strings.Contains(string(data), "second") {
+		t.Fatalf("old file %q", data)
+	}
+	if err := os.Mkdir(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Reopen(); err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	write("third")
+	if data, _ := os.ReadFile(filepath.Join(dir, "log.log")); !strings.Contains(string(data), "third") || strings.Contains(string(data), "second") {
+		t.Fatalf("reopened file %q", data)
+	}
+}
@@ -0,0 +1,161 @@
+package logger
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"time"
+)
+
+const (
+	defaultFluentTag        = "logger"
+	defaultFluentAckTimeout = 10 * time.Second
+	fluentChunkSize         = 24 // 16字节随机数的base64长度
+)
+
+// NewFluent 创建 Fluentd Forward 协议输出，network 支持 tcp, tls, unix
+func NewFluent(network, address, tag string) *Fluent {
+	f := &Fluent{Conn: &Conn{Network: network, Address: address}, Tag: tag}
+	f.Conn.setup = f.setup
+	f.Conn.pack = f.pack
+	f.Conn.bundle = f.bundle
+	f.Conn.transmit = f.transmit
+	return f
+}
+
+// Fluent Fluentd/fluent-bit Forward 协议(MessagePack)输出，连接、重连和队列由 Conn 负责
+// BatchSize 大于1时使用 PackedForward 模式批量发送，否则使用 Message 模式 [tag, time, record]
+// RequireAck 开启后每次发送都等待服务器应答，未收到应答时重连后重发，保证至少一次送达
+// 需要在首次写入前完成配置
+type Fluent struct {
+	*Conn
+	Tag        string        `json:"tag"`        //默认 logger
+	BatchSize  int           `json:"batchSize"`  //每次最多发送的日志条数，大于1时使用 PackedForward 模式
+	RequireAck bool          `json:"requireAck"` //是否等待服务器应答
+	AckTimeout time.Duration `json:"ackTimeout"` //等待应答超时，默认10秒
+}
+
+func (f *Fluent) Name() string {
+	return "fluent+" + f.Conn.Name()
+}
+
+func (f *Fluent) setup() {
+	f.Conn.batch = max(f.BatchSize, 1)
+}
+
+// pack 编码单条日志的 time 和 record，由 bundle 组装成完整的消息
+func (f *Fluent) pack(buf *Buffer, msg *Message) {
+	appendMsgpackEventTime(buf, msg.Time)
+	n := 2 + len(msg.Fields)
+	if msg.Name != "" {
+		n++
+	}
+	if msg.Path != "" {
+		n++
+	}
+	if msg.Stack != "" {
+		n++
+	}
+	appendMsgpackMapHeader(buf, n)
+	appendMsgpackString(buf, "level")
+	appendMsgpackString(buf, msg.Level.Name())
+	if msg.Name != "" {
+		appendMsgpackString(buf, "logger")
+		appendMsgpackString(buf, msg.Name)
+	}
+	if msg.Path != "" {
+		appendMsgpackString(buf, "path")
+		appendMsgpackString(buf, msg.Path)
+	}
+	appendMsgpackString(buf, "msg")
+	appendMsgpackString(buf, msg.Content)
+	for _, field := range msg.Fields {
+		// 与 JSONEncoder 相同，和记录中其他key同名的字段加上 fields. 前缀
+		key := field.Key
+		if jsonReservedKeys[key] {
+			key = "fields." + key
+		}
+		appendMsgpackString(buf, key)
+		appendMsgpackValue(buf, field.Value)
+	}
+	if msg.Stack != "" {
+		appendMsgpackString(buf, "stack")
+		appendMsgpackString(buf, msg.Stack)
+	}
+}
+
+// bundle 组装 Message 或者 PackedForward 消息，chunk 总是放在消息的最后，transmit 据此读取
+func (f *Fluent) bundle(bufs []*Buffer) *Buffer {
+	tag := f.Tag
+	if tag == "" {
+		tag = defaultFluentTag
+	}
+	r := NewBuffer()
+	if f.BatchSize <= 1 && len(bufs) == 1 {
+		// Message 模式: [tag, time, record, option?]
+		if f.RequireAck {
+			appendMsgpackArrayHeader(r, 4)
+		} else {
+			appendMsgpackArrayHeader(r, 3)
+		}
+		appendMsgpackString(r, tag)
+		r.Write(bufs[0].B)
+		if f.RequireAck {
+			appendMsgpackMapHeader(r, 1)
+			f.appendChunk(r)
+		}
+		return r
+	}
+	// PackedForward 模式: [tag, bin([time, record]...), option]
+	entries := NewBuffer()
+	defer entries.Free()
+	for _, b := range bufs {
+		appendMsgpackArrayHeader(entries, 2)
+		entries.Write(b.B)
+	}
+	appendMsgpackArrayHeader(r, 3)
+	appendMsgpackString(r, tag)
+	appendMsgpackBinary(r, entries.B)
+	if f.RequireAck {
+		appendMsgpackMapHeader(r, 2)
+	} else {
+		appendMsgpackMapHeader(r, 1)
+	}
+	appendMsgpackString(r, "size")
+	appendMsgpackInt(r, int64(len(bufs)))
+	if f.RequireAck {
+		f.appendChunk(r)
+	}
+	return r
+}
+
+func (f *Fluent) appendChunk(buf *Buffer) {
+	var id [16]byte
+	_, _ = rand.Read(id[:])
+	appendMsgpackString(buf, "chunk")
+	appendMsgpackString(buf, base64.StdEncoding.EncodeToString(id[:]))
+}
+
+// transmit 写入消息，RequireAck 时等待服务器返回 {"ack": chunk}
+func (f *Fluent) transmit(conn net.Conn, p []byte) error {
+	if _, err := conn.Write(p); err != nil {
+		return err
+	}
+	if !f.RequireAck || len(p) < fluentChunkSize {
+		return nil
+	}
+	timeout := f.AckTimeout
+	if timeout <= 0 {
+		timeout = defaultFluentAckTimeout
+	}
+	_ = conn.SetReadDeadline(time.Now().Add(timeout))
+	m, err := readMsgpackStringMap(conn)
+	if err != nil {
+		return err
+	}
+	if chunk := string(p[len(p)-fluentChunkSize:]); m["ack"] != chunk {
+		return fmt.Errorf("fluent ack mismatch:%v", m["ack"])
+	}
+	return nil
+}
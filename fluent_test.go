@@ -0,0 +1,343 @@
+package logger
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fluentEntry 服务器收到的一条日志
+type fluentEntry struct {
+	tag    string
+	time   time.Time
+	record map[string]any
+	packed bool
+}
+
+// listenFluent 启动本地 Forward 服务，ack 为 true 时应答 chunk，reject 次数内的消息不应答直接断开
+func listenFluent(t *testing.T, ack bool, reject int) (net.Listener, chan fluentEntry) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	entries := make(chan fluentEntry, 1000)
+	var rejected atomic.Int32
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				for {
+					v, err := decodeMsgpack(r)
+					if err != nil {
+						return
+					}
+					msg := v.([]any)
+					tag := msg[0].(string)
+					var option map[string]any
+					var received []fluentEntry
+					if p, ok := msg[1].([]byte); ok {
+						pr := bytes.NewReader(p)
+						for pr.Len() > 0 {
+							e, err := decodeMsgpack(pr)
+							if err != nil {
+								t.Errorf("decode packed entry: %v", err)
+								return
+							}
+							pair := e.([]any)
+							received = append(received, fluentEntry{tag: tag, time: pair[0].(time.Time), record: pair[1].(map[string]any), packed: true})
+						}
+						option = msg[2].(map[string]any)
+						if option["size"] != int64(len(received)) {
+							t.Errorf("packed size %v, entries %d", option["size"], len(received))
+						}
+					} else {
+						received = append(received, fluentEntry{tag: tag, time: msg[1].(time.Time), record: msg[2].(map[string]any)})
+						if len(msg) > 3 {
+							option = msg[3].(map[string]any)
+						}
+					}
+					if ack {
+						if int(rejected.Add(1)) <= reject {
+							return
+						}
+						b := NewBuffer()
+						appendMsgpackMapHeader(b, 1)
+						appendMsgpackString(b, "ack")
+						appendMsgpackString(b, option["chunk"].(string))
+						_, _ = conn.Write(b.B)
+						b.Free()
+					}
+					for _, e := range received {
+						entries <- e
+					}
+				}
+			}()
+		}
+	}()
+	t.Cleanup(func() { _ = ln.Close() })
+	return ln, entries
+}
+
+func receiveFluent(t *testing.T, entries chan fluentEntry) fluentEntry {
+	t.Helper()
+	select {
+	case e := <-entries:
+		return e
+	case <-time.After(3 * time.Second):
+		t.Fatalf("receive entry timeout")
+	}
+	return fluentEntry{}
+}
+
+func TestFluentMessage(t *testing.T) {
+	ln, entries := listenFluent(t, false, 0)
+	f := NewFluent("tcp", ln.Addr().String(), "app.game")
+	log := New()
+	log.SetLevel(LevelDebug)
+	if err := log.SetOutput(f.Name(), f); err != nil {
+		t.Fatalf("SetOutput: %v", err)
+	}
+	now := time.Now()
+	log.Named("room").Infow("login", "uid", 42, "ok", true, "cost", 1.5)
+	if err := log.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	e := receiveFluent(t, entries)
+	if e.tag != "app.game" || e.packed {
+		t.Fatalf("unexpected entry %+v", e)
+	}
+	if d := e.time.Sub(now); d < 0 || d > time.Second {
+		t.Fatalf("unexpected time %v", e.time)
+	}
+	want := map[string]any{"level": "INFO", "logger": "room", "msg": "login", "uid": int64(42), "ok": true, "cost": 1.5}
+	for k, v := range want {
+		if e.record[k] != v {
+			t.Fatalf("record[%s] = %v, want %v: %v", k, e.record[k], v, e.record)
+		}
+	}
+	if e.record["path"] == nil {
+		t.Fatalf("missing path: %v", e.record)
+	}
+	_ = log.Close()
+}
+
+func TestFluentPackedForward(t *testing.T) {
+	ln, entries := listenFluent(t, false, 0)
+	f := NewFluent("tcp", ln.Addr().String(), "")
+	f.BatchSize = 8
+	for i := 0; i < 20; i++ {
+		f.Write(&Message{Content: fmt.Sprintf("m%d", i), Level: LevelWarn, Time: time.Now()})
+	}
+	if err := f.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	for i := 0; i < 20; i++ {
+		e := receiveFluent(t, entries)
+		if e.tag != defaultFluentTag || !e.packed || e.record["msg"] != fmt.Sprintf("m%d", i) || e.record["level"] != "WARN" {
+			t.Fatalf("unexpected entry %d: %+v", i, e)
+		}
+	}
+	_ = f.Close()
+}
+
+func TestFluentAck(t *testing.T) {
+	for _, batch := range []int{0, 4} {
+		ln, entries := listenFluent(t, true, 1)
+		f := NewFluent("tcp", ln.Addr().String(), "ack")
+		f.RequireAck = true
+		f.BatchSize = batch
+		f.AckTimeout = time.Second
+		f.Backoff = Backoff{Initial: 10 * time.Millisecond, Max: 50 * time.Millisecond}
+		f.Write(&Message{Content: "once", Level: LevelInfo, Time: time.Now()})
+		// 第一次发送没有收到应答，重连后重发
+		if e := receiveFluent(t, entries); e.record["msg"] != "once" {
+			t.Fatalf("batch %d: unexpected entry %+v", batch, e)
+		}
+		if err := f.Close(); err != nil {
+			t.Fatalf("batch %d: Close: %v", batch, err)
+		}
+	}
+}
+
+func TestFluentReservedFields(t *testing.T) {
+	f := NewFluent("tcp", "127.0.0.1:24224", "")
+	buf := NewBuffer()
+	defer buf.Free()
+	msg := newEncoderMessage()
+	msg.Fields = []Field{String("level", "a"), String("logger", "b"), String("path", "c"), String("msg", "d"), String("stack", "e")}
+	msg.Stack = "goroutine 1\n"
+	f.pack(buf, msg)
+	r := bytes.NewReader(buf.B)
+	if _, err := decodeMsgpack(r); err != nil {
+		t.Fatalf("decode time: %v", err)
+	}
+	v, err := decodeMsgpack(r)
+	if err != nil {
+		t.Fatalf("decode record: %v", err)
+	}
+	record := v.(map[string]any)
+	want := map[string]any{
+		"level": msg.Level.Name(), "logger": msg.Name, "path": msg.Path, "msg": msg.Content, "stack": msg.Stack,
+		"fields.level": "a", "fields.logger": "b", "fields.path": "c", "fields.msg": "d", "fields.stack": "e",
+	}
+	if len(record) != len(want) {
+		t.Fatalf("record = %v", record)
+	}
+	for k, v := range want {
+		if record[k] != v {
+			t.Errorf("record[%s] = %v, want %v", k, record[k], v)
+		}
+	}
+}
+
+func TestMsgpackReadLimit(t *testing.T) {
+	// 长度前缀为 4GiB 的字符串和map直接返回错误，不会按长度分配内存
+	for _, p := range [][]byte{
+		{0x81, 0xdb, 0xff, 0xff, 0xff, 0xff},
+		{0xdf, 0xff, 0xff, 0xff, 0xff},
+	} {
+		if _, err := readMsgpackStringMap(bytes.NewReader(p)); err == nil {
+			t.Errorf("readMsgpackStringMap(% x) should fail", p)
+		}
+	}
+	m, err := readMsgpackStringMap(bytes.NewReader([]byte{0x81, 0xa3, 'a', 'c', 'k', 0xa2, 'i', 'd'}))
+	if err != nil || m["ack"] != "id" {
+		t.Errorf("readMsgpackStringMap = %v, %v", m, err)
+	}
+}
+
+// decodeMsgpack 测试用的 MessagePack 解码，整数统一为 int64，EventTime 解码为 time.Time
+func decodeMsgpack(r io.Reader) (any, error) {
+	read := func(n int) ([]byte, error) {
+		b := make([]byte, n)
+		_, err := io.ReadFull(r, b)
+		return b, err
+	}
+	length := func(n int) (int, error) {
+		b, err := read(n)
+		if err != nil {
+			return 0, err
+		}
+		switch n {
+		case 1:
+			return int(b[0]), nil
+		case 2:
+			return int(binary.BigEndian.Uint16(b)), nil
+		}
+		return int(binary.BigEndian.Uint32(b)), nil
+	}
+	array := func(n int) (any, error) {
+		a := make([]any, n)
+		for i := range a {
+			v, err := decodeMsgpack(r)
+			if err != nil {
+				return nil, err
+			}
+			a[i] = v
+		}
+		return a, nil
+	}
+	object := func(n int) (any, error) {
+		m := make(map[string]any, n)
+		for i := 0; i < n; i++ {
+			k, err := decodeMsgpack(r)
+			if err != nil {
+				return nil, err
+			}
+			if m[k.(string)], err = decodeMsgpack(r); err != nil {
+				return nil, err
+			}
+		}
+		return m, nil
+	}
+	b, err := read(1)
+	if err != nil {
+		return nil, err
+	}
+	c := b[0]
+	switch {
+	case c < 0x80:
+		return int64(c), nil
+	case c >= 0xe0:
+		return int64(int8(c)), nil
+	case c&0xf0 == 0x80:
+		return object(int(c & 0x0f))
+	case c&0xf0 == 0x90:
+		return array(int(c & 0x0f))
+	case c&0xe0 == 0xa0:
+		b, err := read(int(c & 0x1f))
+		return string(b), err
+	}
+	var n int
+	switch c {
+	case 0xc0:
+		return nil, nil
+	case 0xc2, 0xc3:
+		return c == 0xc3, nil
+	case 0xc4, 0xc5, 0xc6, 0xd9, 0xda, 0xdb:
+		size := map[byte]int{0xc4: 1, 0xc5: 2, 0xc6: 4, 0xd9: 1, 0xda: 2, 0xdb: 4}[c]
+		if n, err = length(size); err != nil {
+			return nil, err
+		}
+		b, err := read(n)
+		if c >= 0xd9 {
+			return string(b), err
+		}
+		return b, err
+	case 0xca:
+		b, err := read(4)
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(b))), err
+	case 0xcb:
+		b, err := read(8)
+		return math.Float64frombits(binary.BigEndian.Uint64(b)), err
+	case 0xcc, 0xcd, 0xce:
+		n, err = length(map[byte]int{0xcc: 1, 0xcd: 2, 0xce: 4}[c])
+		return int64(n), err
+	case 0xcf:
+		b, err := read(8)
+		return int64(binary.BigEndian.Uint64(b)), err
+	case 0xd0:
+		b, err := read(1)
+		return int64(int8(b[0])), err
+	case 0xd1:
+		b, err := read(2)
+		return int64(int16(binary.BigEndian.Uint16(b))), err
+	case 0xd2:
+		b, err := read(4)
+		return int64(int32(binary.BigEndian.Uint32(b))), err
+	case 0xd3:
+		b, err := read(8)
+		return int64(binary.BigEndian.Uint64(b)), err
+	case 0xd7:
+		b, err := read(9)
+		if err != nil || b[0] != 0 {
+			return nil, fmt.Errorf("unexpected ext %v %v", b, err)
+		}
+		return time.Unix(int64(binary.BigEndian.Uint32(b[1:5])), int64(binary.BigEndian.Uint32(b[5:]))), nil
+	case 0xdc, 0xdd:
+		if n, err = length(map[byte]int{0xdc: 2, 0xdd: 4}[c]); err != nil {
+			return nil, err
+		}
+		return array(n)
+	case 0xde, 0xdf:
+		if n, err = length(map[byte]int{0xde: 2, 0xdf: 4}[c]); err != nil {
+			return nil, err
+		}
+		return object(n)
+	}
+	return nil, fmt.Errorf("unexpected msgpack type 0x%x", c)
+}
@@ -0,0 +1,180 @@
+package logger
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const (
+	defaultGELFChunkSize = 1420 // 默认UDP分块大小，适合以太网MTU
+	gelfChunkHeaderSize  = 12   // magic(2) + id(8) + seq(1) + count(1)
+	gelfMaxChunks        = 128
+)
+
+// GELFCompression GELF UDP 压缩方式，TCP 不支持压缩
+type GELFCompression int8
+
+const (
+	GELFCompressNone GELFCompression = 0
+	GELFCompressGzip GELFCompression = 1
+	GELFCompressZlib GELFCompression = 2
+)
+
+// NewGELF 创建 GELF 输出，network 支持 udp, tcp, tls
+func NewGELF(network, address string) *GELF {
+	g := &GELF{Conn: &Conn{Network: network, Address: address}}
+	g.Host, _ = os.Hostname()
+	g.Conn.pack = g.pack
+	if g.udp() {
+		g.Conn.transmit = g.transmit
+	}
+	return g
+}
+
+// GELF Graylog GELF 1.1 输出，连接、重连和队列由 Conn 负责
+// UDP 超过 ChunkSize 时分块发送，可选 gzip/zlib 压缩; TCP 以 \0 分隔
+// 需要在首次写入前完成配置
+type GELF struct {
+	*Conn
+	Host        string          `json:"host"`        //来源主机名，默认本机主机名
+	Compression GELFCompression `json:"compression"` //UDP压缩方式，默认不压缩
+	ChunkSize   int             `json:"chunkSize"`   //UDP分块大小，默认1420
+}
+
+func (g *GELF) Name() string {
+	return "gelf+" + g.Conn.Name()
+}
+
+func (g *GELF) udp() bool {
+	return strings.HasPrefix(g.Network, "udp")
+}
+
+// pack 编码，UDP 按配置压缩，TCP 以 \0 结尾
+func (g *GELF) pack(buf *Buffer, msg *Message) {
+	if !g.udp() {
+		_ = g.Encode(buf, msg)
+		buf.WriteByte(0)
+		return
+	}
+	if g.Compression == GELFCompressNone {
+		_ = g.Encode(buf, msg)
+		return
+	}
+	b := NewBuffer()
+	defer b.Free()
+	_ = g.Encode(b, msg)
+	var w io.WriteCloser
+	if g.Compression == GELFCompressZlib {
+		w = zlib.NewWriter(buf)
+	} else {
+		w = gzip.NewWriter(buf)
+	}
+	_, _ = w.Write(b.B)
+	_ = w.Close()
+}
+
+// transmit UDP 分块发送，超过128块的日志被丢弃并计入 Dropped
+func (g *GELF) transmit(conn net.Conn, p []byte) error {
+	size := g.ChunkSize
+	if size <= gelfChunkHeaderSize {
+		size = defaultGELFChunkSize
+	}
+	if len(p) <= size {
+		_, err := conn.Write(p)
+		return err
+	}
+	size -= gelfChunkHeaderSize
+	count := (len(p) + size - 1) / size
+	if count > gelfMaxChunks {
+		return fmt.Errorf("%w: gelf message needs %d chunks", errConnDropped, count)
+	}
+	chunk := make([]byte, gelfChunkHeaderSize+size)
+	chunk[0], chunk[1] = 0x1e, 0x0f
+	binary.BigEndian.PutUint64(chunk[2:10], rand.Uint64())
+	chunk[11] = byte(count)
+	for i := 0; i < count; i++ {
+		chunk[10] = byte(i)
+		n := copy(chunk[gelfChunkHeaderSize:], p[i*size:])
+		if _, err := conn.Write(chunk[:gelfChunkHeaderSize+n]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Encode GELF 1.1 JSON, Path 转换为 _file 和 _line, Stack 作为 full_message, 字段以 _ 开头
+func (g *GELF) Encode(buf *Buffer, msg *Message) error {
+	buf.WriteString(`{"version":"1.1","host":`)
+	host := g.Host
+	if host == "" {
+		host = "unknown"
+	}
+	appendJSONString(buf, host)
+	buf.WriteString(`,"short_message":`)
+	appendJSONString(buf, msg.Content)
+	if msg.Stack != "" {
+		buf.WriteString(`,"full_message":`)
+		appendJSONString(buf, msg.Stack)
+	}
+	buf.WriteString(`,"timestamp":`)
+	ms := msg.Time.Nanosecond() / 1e6
+	buf.AppendInt(msg.Time.Unix())
+	buf.WriteByte('.')
+	buf.WriteByte(byte('0' + ms/100))
+	buf.WriteByte(byte('0' + ms/10%10))
+	buf.WriteByte(byte('0' + ms%10))
+	buf.WriteString(`,"level":`)
+	buf.AppendInt(int64(SyslogSeverity(msg.Level)))
+	if msg.Path != "" {
+		file, line := msg.Path, ""
+		if i := strings.LastIndexByte(file, ':'); i >= 0 {
+			file, line = file[:i], file[i+1:]
+		}
+		writeJSONField(buf, "_file", file)
+		if n, err := strconv.Atoi(line); err == nil {
+			writeJSONField(buf, "_line", n)
+		}
+	}
+	if msg.Name != "" {
+		writeJSONField(buf, "_logger", msg.Name)
+	}
+	buf.WriteString(`,"_level_name":"`)
+	buf.WriteString(msg.Level.Name())
+	buf.WriteByte('"')
+	for _, f := range msg.Fields {
+		writeJSONField(buf, gelfFieldName(f.Key), f.Value)
+	}
+	buf.WriteByte('}')
+	return nil
+}
+
+// gelfFieldName 附加字段名只允许字母、数字、下划线、点和减号，并且不能为 _id 或者与 Encode 使用的字段重名
+func gelfFieldName(key string) string {
+	b := make([]byte, 0, len(key)+1)
+	b = append(b, '_')
+	for i := 0; i < len(key); i++ {
+		c := key[i]
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '.' || c == '-') {
+			c = '_'
+		}
+		b = append(b, c)
+	}
+	if string(b) == "_id" {
+		return "_id_"
+	}
+	if gelfReservedKeys[string(b)] {
+		return "_fields." + string(b[1:])
+	}
+	return string(b)
+}
+
+// gelfReservedKeys Encode 使用的附加字段，同名的字段加上 fields. 前缀，与 JSONEncoder 一致
+var gelfReservedKeys = map[string]bool{"_file": true, "_line": true, "_logger": true, "_level_name": true}
@@ -0,0 +1,194 @@
+package logger
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/json"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newGELFMessage 在 newEncoderMessage 的基础上设置 GELF 测试需要的等级、堆栈和字段
+func newGELFMessage(content string) *Message {
+	msg := newEncoderMessage()
+	msg.Time = msg.Time.Add(123 * time.Millisecond)
+	msg.Level = LevelError
+	msg.Content = content
+	msg.Stack = "goroutine 1\n"
+	msg.Fields = []Field{Int("uid", 7), String("id", "x"), String("bad key", "v")}
+	return msg
+}
+
+func TestGELFEncode(t *testing.T) {
+	g := NewGELF("udp", "127.0.0.1:12201")
+	g.Host = "host"
+	buf := NewBuffer()
+	defer buf.Free()
+	_ = g.Encode(buf, newGELFMessage("hello"))
+	want := `{"version":"1.1","host":"host","short_message":"hello","full_message":"goroutine 1\n","timestamp":1714979289.123,"level":3,"_file":"game/room.go","_line":12,"_logger":"game","_level_name":"ERROR","_uid":7,"_id_":"x","_bad_key":"v"}`
+	if buf.String() != want {
+		t.Errorf("Encode:\n got %s\nwant %s", buf, want)
+	}
+}
+
+func TestGELFReservedFields(t *testing.T) {
+	g := NewGELF("udp", "127.0.0.1:12201")
+	buf := NewBuffer()
+	defer buf.Free()
+	msg := newGELFMessage("hello")
+	msg.Fields = []Field{String("file", "a.go"), Int("line", 1), String("logger", "x"), String("level_name", "y")}
+	_ = g.Encode(buf, msg)
+	var m map[string]any
+	if err := json.Unmarshal(buf.B, &m); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if m["_file"] != "game/room.go" || m["_line"] != float64(12) || m["_logger"] != "game" || m["_level_name"] != "ERROR" {
+		t.Errorf("reserved fields overwritten: %s", buf)
+	}
+	if m["_fields.file"] != "a.go" || m["_fields.line"] != float64(1) || m["_fields.logger"] != "x" || m["_fields.level_name"] != "y" {
+		t.Errorf("fields not prefixed: %s", buf)
+	}
+	if n := strings.Count(buf.String(), `"_file"`); n != 1 {
+		t.Errorf("_file appears %d times", n)
+	}
+}
+
+// readGELFUDP 接收并合并分块，按压缩格式解压
+func readGELFUDP(t *testing.T, pc net.PacketConn) map[string]any {
+	t.Helper()
+	_ = pc.SetReadDeadline(time.Now().Add(3 * time.Second))
+	chunks := map[byte][]byte{}
+	p := make([]byte, 65536)
+	var data []byte
+	for {
+		n, _, err := pc.ReadFrom(p)
+		if err != nil {
+			t.Fatalf("read: %v", err)
+		}
+		if n < 2 || p[0] != 0x1e || p[1] != 0x0f {
+			data = append([]byte(nil), p[:n]...)
+			break
+		}
+		chunks[p[10]] = append([]byte(nil), p[12:n]...)
+		if count := int(p[11]); len(chunks) == count {
+			for i := 0; i < count; i++ {
+				data = append(data, chunks[byte(i)]...)
+			}
+			break
+		}
+	}
+	var r io.Reader = bytes.NewReader(data)
+	switch {
+	case len(data) > 2 && data[0] == 0x1f && data[1] == 0x8b:
+		r, _ = gzip.NewReader(r)
+	case len(data) > 0 && data[0] == 0x78:
+		r, _ = zlib.NewReader(r)
+	}
+	var m map[string]any
+	if err := json.NewDecoder(r).Decode(&m); err != nil {
+		t.Fatalf("decode %q: %v", data, err)
+	}
+	return m
+}
+
+func TestGELFUDP(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer pc.Close()
+	tests := []struct {
+		compression GELFCompression
+		content     string
+	}{
+		{GELFCompressNone, "short"},
+		{GELFCompressNone, strings.Repeat("chunked ", 1000)},
+		{GELFCompressGzip, "gzip"},
+		{GELFCompressZlib, "zlib"},
+	}
+	for _, tt := range tests {
+		g := NewGELF("udp", pc.LocalAddr().String())
+		g.Compression = tt.compression
+		g.ChunkSize = 512
+		g.Write(newGELFMessage(tt.content))
+		m := readGELFUDP(t, pc)
+		if m["short_message"] != tt.content || m["_uid"] != float64(7) {
+			t.Errorf("compression %v: unexpected message %v", tt.compression, m)
+		}
+		_ = g.Close()
+	}
+}
+
+func TestGELFTooManyChunks(t *testing.T) {
+	// 广播到多个地址时，无法发送的日志只计入一次丢弃
+	var pcs []net.PacketConn
+	var addrs []string
+	for i := 0; i < 2; i++ {
+		pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("listen: %v", err)
+		}
+		defer pc.Close()
+		pcs = append(pcs, pc)
+		addrs = append(addrs, pc.LocalAddr().String())
+	}
+	g := NewGELF("udp", strings.Join(addrs, ";"))
+	g.Strategy = StrategyBroadcast
+	g.ChunkSize = 100
+	g.Write(newGELFMessage(strings.Repeat("x", 100*gelfMaxChunks)))
+	g.Write(newGELFMessage("after"))
+	for _, pc := range pcs {
+		if m := readGELFUDP(t, pc); m["short_message"] != "after" {
+			t.Fatalf("unexpected message %v", m)
+		}
+	}
+	_ = g.Close()
+	if g.Dropped() != 1 {
+		t.Fatalf("Dropped = %d", g.Dropped())
+	}
+	for _, ep := range g.Endpoints() {
+		if ep.Sent != 1 || ep.Failures != 0 {
+			t.Fatalf("endpoints = %+v", g.Endpoints())
+		}
+	}
+}
+
+func TestGELFTCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer ln.Close()
+	frames := make(chan string, 10)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		for {
+			frame, err := r.ReadString(0)
+			if err != nil {
+				return
+			}
+			frames <- strings.TrimSuffix(frame, "\x00")
+		}
+	}()
+	g := NewGELF("tcp", ln.Addr().String())
+	defer g.Close()
+	for i := 0; i < 2; i++ {
+		g.Write(newGELFMessage("tcp"))
+	}
+	for i := 0; i < 2; i++ {
+		var m map[string]any
+		if err := json.Unmarshal([]byte(receiveLine(t, frames)), &m); err != nil || m["short_message"] != "tcp" {
+			t.Errorf("unexpected frame %v, %v", m, err)
+		}
+	}
+}
@@ -0,0 +1,399 @@
+package logger
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultHTTPQueueSize  = 10000
+	defaultHTTPBatchSize  = 100
+	defaultHTTPBatchBytes = 1024 * 1024
+	defaultHTTPInterval   = time.Second
+	defaultHTTPTimeout    = 10 * time.Second
+	defaultHTTPRetries    = 3
+)
+
+// HTTPFormat HTTP 请求体格式
+type HTTPFormat int8
+
+const (
+	HTTPJSONLines HTTPFormat = 0 // 每行一个JSON对象(application/x-ndjson)
+	HTTPJSONArray HTTPFormat = 1 // JSON数组(application/json)
+)
+
+// httpStatusError 服务器返回的错误状态
+type httpStatusError struct {
+	code       int
+	body       string
+	retryAfter time.Duration
+}
+
+func (e *httpStatusError) Error() string {
+	return fmt.Sprintf("http status %d:%s", e.code, e.body)
+}
+
+// retryable 网络错误、5xx和429需要重试
+func (e *httpStatusError) retryable() bool {
+	return e.code >= 500 || e.code == http.StatusTooManyRequests
+}
+
+// httpEntry 队列中的日志，data 为单条日志编码后的内容
+type httpEntry struct {
+	msg  Message
+	data *Buffer
+}
+
+func NewHTTP(url string, cap ...int) *HTTP {
+	h := &HTTP{URL: url}
+	if len(cap) > 0 {
+		h.QueueSize = cap[0]
+	}
+	return h
+}
+
+// HTTP 批量推送日志的输出，日志在调用方协程中编码后放入队列，由独立协程按数量、大小或者时间间隔打包发送
+// 网络错误、5xx和429按 Backoff 重试，队列满或者重试失败的日志被丢弃
+// 发送协程在首次写入时启动，需要在首次写入前完成配置
+type HTTP struct {
+	URL        string                       `json:"url"`
+	Method     string                       `json:"method"`     //默认 POST
+	Header     http.Header                  `json:"header"`     //附加请求头，例如认证 Authorization
+	Format     HTTPFormat                   `json:"format"`     //请求体格式，默认 HTTPJSONLines
+	Gzip       bool                         `json:"gzip"`       //是否使用gzip压缩请求体
+	QueueSize  int                          `json:"queueSize"`  //队列容量，默认10000
+	BatchSize  int                          `json:"batchSize"`  //每批最多日志条数，默认100
+	BatchBytes int                          `json:"batchBytes"` //每批日志编码后的最大字节数，默认1M，超过的单条日志被丢弃
+	Interval   time.Duration                `json:"interval"`   //最长发送间隔，默认1秒
+	Timeout    time.Duration                `json:"timeout"`    //单次请求超时，默认10秒
+	Retries    int                          `json:"retries"`    //失败重试次数，默认3次，小于0时不重试
+	Backoff    Backoff                      `json:"backoff"`    //重试等待策略
+	Client     *http.Client                 `json:"-"`          //默认使用 http.Client{}
+	Encoder    Encoder                      `json:"-"`          //单条日志编码器，默认 JSONEncoder
+	OnError    func(err error, dropped int) //发送失败回调，未设置时输出到stderr
+
+	once    sync.Once
+	mutex   sync.RWMutex                                            //保护closed和queue的关闭
+	closed  bool                                                    //是否已经关闭
+	queue   chan *httpEntry                                         //发送队列
+	flush   chan chan error                                         //刷新请求,由process处理后返回结果
+	closing chan struct{}                                           //Close时关闭，中断重试等待
+	done    chan struct{}                                           //process退出后关闭
+	dropped atomic.Int64                                            //丢弃的日志数量
+	encode  func(buf *Buffer, msg *Message)                         //单条日志编码，供 Loki 等输出替换
+	marshal func(w io.Writer, entries []*httpEntry) (string, error) //生成请求体并返回 Content-Type
+}
+
+func (h *HTTP) Name() string {
+	return h.URL
+}
+
+func (h *HTTP) start() {
+	h.once.Do(func() {
+		size := h.QueueSize
+		if size <= 0 {
+			size = defaultHTTPQueueSize
+		}
+		if h.encode == nil {
+			h.encode = h.encodeJSON
+		}
+		if h.marshal == nil {
+			h.marshal = h.marshalJSON
+		}
+		h.queue = make(chan *httpEntry, size)
+		h.flush = make(chan chan error)
+		h.closing = make(chan struct{})
+		h.done = make(chan struct{})
+		go h.process()
+	})
+}
+
+// Dropped 丢弃的日志数量
+func (h *HTTP) Dropped() int64 {
+	return h.dropped.Load()
+}
+
+func (h *HTTP) Write(msg *Message) {
+	h.start()
+	e := &httpEntry{msg: *msg, data: NewBuffer()}
+	h.encode(e.data, msg)
+
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+	if h.closed {
+		e.data.Free()
+		return
+	}
+	select {
+	case h.queue <- e:
+	default:
+		e.data.Free()
+		h.dropped.Add(1)
+	}
+}
+
+// Flush 发送队列中已有的日志，返回最后一批的发送结果
+func (h *HTTP) Flush(ctx context.Context) error {
+	h.start()
+	result := make(chan error, 1)
+	select {
+	case h.flush <- result:
+	case <-h.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case err := <-result:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close 发送队列中剩余的日志后退出，重试等待会被中断，可以重复调用
+func (h *HTTP) Close() error {
+	h.start()
+	h.mutex.Lock()
+	if !h.closed {
+		h.closed = true
+		close(h.queue)
+		close(h.closing)
+	}
+	h.mutex.Unlock()
+	<-h.done
+	return nil
+}
+
+func (h *HTTP) process() {
+	defer close(h.done)
+	interval := h.Interval
+	if interval <= 0 {
+		interval = defaultHTTPInterval
+	}
+	batchSize, batchBytes := h.BatchSize, h.BatchBytes
+	if batchSize <= 0 {
+		batchSize = defaultHTTPBatchSize
+	}
+	if batchBytes <= 0 {
+		batchBytes = defaultHTTPBatchBytes
+	}
+	timer := time.NewTimer(interval)
+	defer timer.Stop()
+
+	var batch []*httpEntry
+	size := 0
+	// 分隔符计入批次大小：每条日志加上一个换行符或者逗号，JSON数组再加上两端的括号
+	overhead := 0
+	if h.Format == HTTPJSONArray {
+		overhead = 2
+	}
+	// add 加入批次，加入后会超出大小时先发送已有的批次，达到数量或者大小时立即发送
+	// 单条日志超过 BatchBytes 时直接丢弃
+	add := func(e *httpEntry) (err error) {
+		n := e.data.Len() + 1
+		if overhead+n > batchBytes {
+			e.data.Free()
+			err = fmt.Errorf("http entry size %d exceeds batch bytes %d", n-1, batchBytes)
+			h.drop(err, 1)
+			return
+		}
+		if len(batch) > 0 && overhead+size+n > batchBytes {
+			err = h.send(batch)
+			batch, size = batch[:0], 0
+		}
+		batch = append(batch, e)
+		size += n
+		if len(batch) >= batchSize || overhead+size >= batchBytes {
+			if e := h.send(batch); e != nil {
+				err = e
+			}
+			batch, size = batch[:0], 0
+		}
+		return
+	}
+	for {
+		select {
+		case e, ok := <-h.queue:
+			if !ok {
+				_ = h.send(batch)
+				return
+			}
+			_ = add(e)
+		case result := <-h.flush:
+			var err error
+			for n := len(h.queue); n > 0; n-- {
+				e, ok := <-h.queue
+				if !ok {
+					break
+				}
+				if e := add(e); e != nil {
+					err = e
+				}
+			}
+			if e := h.send(batch); e != nil {
+				err = e
+			}
+			batch, size = batch[:0], 0
+			result <- err
+		case <-timer.C:
+			_ = h.send(batch)
+			batch, size = batch[:0], 0
+			timer.Reset(interval)
+		}
+	}
+}
+
+// send 发送一批日志，失败时按 Backoff 重试
+func (h *HTTP) send(batch []*httpEntry) (err error) {
+	if len(batch) == 0 {
+		return nil
+	}
+	defer func() {
+		for _, e := range batch {
+			e.data.Free()
+			e.data = nil
+		}
+		if err != nil {
+			h.drop(err, len(batch))
+		}
+	}()
+	body := &bytes.Buffer{}
+	var contentType string
+	if h.Gzip {
+		w := gzip.NewWriter(body)
+		if contentType, err = h.marshal(w, batch); err != nil {
+			return err
+		}
+		if err = w.Close(); err != nil {
+			return err
+		}
+	} else if contentType, err = h.marshal(body, batch); err != nil {
+		return err
+	}
+
+	retries := h.Retries
+	if retries == 0 {
+		retries = defaultHTTPRetries
+	}
+	var wait time.Duration
+	for i := 0; ; i++ {
+		err = h.post(body.Bytes(), contentType)
+		var status *httpStatusError
+		if err == nil || i >= retries || errors.As(err, &status) && !status.retryable() {
+			return err
+		}
+		wait = h.Backoff.Next(wait)
+		delay := h.Backoff.jitter(wait)
+		if status != nil && status.retryAfter > 0 {
+			delay = min(status.retryAfter, h.Backoff.limit())
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-h.closing:
+			timer.Stop()
+			return err
+		}
+	}
+}
+
+// drop 记录丢弃的日志并通知 OnError
+func (h *HTTP) drop(err error, n int) {
+	h.dropped.Add(int64(n))
+	if h.OnError != nil {
+		h.OnError(err, n)
+	} else {
+		_, _ = fmt.Fprintf(os.Stderr, "logger http %v dropped %d:%v\n", h.URL, n, err)
+	}
+}
+
+func (h *HTTP) post(body []byte, contentType string) error {
+	timeout := h.Timeout
+	if timeout <= 0 {
+		timeout = defaultHTTPTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	method := h.Method
+	if method == "" {
+		method = http.MethodPost
+	}
+	req, err := http.NewRequestWithContext(ctx, method, h.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	for k, v := range h.Header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Content-Type", contentType)
+	if h.Gzip {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+	client := h.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	err = &httpStatusError{code: resp.StatusCode, body: string(msg)}
+	if s := resp.Header.Get("Retry-After"); s != "" {
+		if n, e := strconv.Atoi(s); e == nil && n > 0 {
+			err.(*httpStatusError).retryAfter = time.Duration(n) * time.Second
+		}
+	}
+	return err
+}
+
+func (h *HTTP) encodeJSON(buf *Buffer, msg *Message) {
+	if h.Encoder != nil {
+		encode(h.Encoder, buf, msg)
+	} else {
+		_ = defaultJSONEncoder.Encode(buf, msg)
+	}
+}
+
+// marshalJSON 按 Format 拼接单条日志
+func (h *HTTP) marshalJSON(w io.Writer, entries []*httpEntry) (string, error) {
+	if h.Format == HTTPJSONArray {
+		sep := []byte{'['}
+		for _, e := range entries {
+			if _, err := w.Write(sep); err != nil {
+				return "", err
+			}
+			if _, err := w.Write(e.data.B); err != nil {
+				return "", err
+			}
+			sep[0] = ','
+		}
+		_, err := w.Write([]byte{']'})
+		return "application/json", err
+	}
+	for _, e := range entries {
+		if _, err := w.Write(e.data.B); err != nil {
+			return "", err
+		}
+		if _, err := w.Write([]byte{'\n'}); err != nil {
+			return "", err
+		}
+	}
+	return "application/x-ndjson", nil
+}
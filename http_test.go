@@ -0,0 +1,202 @@
+package logger
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// httpRecorder 记录收到的请求体
+type httpRecorder struct {
+	mutex  sync.Mutex
+	bodies [][]byte
+	header []http.Header
+}
+
+func (r *httpRecorder) record(t *testing.T, req *http.Request) {
+	var body io.Reader = req.Body
+	if req.Header.Get("Content-Encoding") == "gzip" {
+		zr, err := gzip.NewReader(req.Body)
+		if err != nil {
+			t.Errorf("gzip: %v", err)
+			return
+		}
+		body = zr
+	}
+	data, _ := io.ReadAll(body)
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.bodies = append(r.bodies, data)
+	r.header = append(r.header, req.Header.Clone())
+}
+
+func (r *httpRecorder) count() int {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return len(r.bodies)
+}
+
+func TestHTTPBatchLines(t *testing.T) {
+	rec := &httpRecorder{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		rec.record(t, req)
+	}))
+	defer srv.Close()
+
+	h := &HTTP{URL: srv.URL, BatchSize: 3, Gzip: true, Header: http.Header{"Authorization": {"Bearer token"}}}
+	for i := 0; i < 7; i++ {
+		h.Write(&Message{Level: LevelInfo, Time: time.Now(), Content: "batch", Fields: []Field{Int("i", i)}})
+	}
+	if err := h.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	_ = h.Close()
+	if rec.count() != 3 {
+		t.Fatalf("requests = %d", rec.count())
+	}
+	n := 0
+	for i, body := range rec.bodies {
+		if rec.header[i].Get("Authorization") != "Bearer token" || rec.header[i].Get("Content-Type") != "application/x-ndjson" {
+			t.Errorf("unexpected header %v", rec.header[i])
+		}
+		scanner := bufio.NewScanner(strings.NewReader(string(body)))
+		for scanner.Scan() {
+			var m map[string]any
+			if err := json.Unmarshal(scanner.Bytes(), &m); err != nil || m["i"] != float64(n) {
+				t.Errorf("line %d = %s, %v", n, scanner.Bytes(), err)
+			}
+			n++
+		}
+	}
+	if n != 7 {
+		t.Errorf("lines = %d", n)
+	}
+}
+
+func TestHTTPBatchArrayInterval(t *testing.T) {
+	rec := &httpRecorder{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		rec.record(t, req)
+	}))
+	defer srv.Close()
+
+	h := NewHTTP(srv.URL)
+	h.Format = HTTPJSONArray
+	h.Interval = 20 * time.Millisecond
+	defer h.Close()
+	h.Write(&Message{Level: LevelInfo, Time: time.Now(), Content: "one"})
+	h.Write(&Message{Level: LevelInfo, Time: time.Now(), Content: "two"})
+	deadline := time.Now().Add(3 * time.Second)
+	for rec.count() == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if rec.count() != 1 {
+		t.Fatalf("requests = %d", rec.count())
+	}
+	var arr []map[string]any
+	if err := json.Unmarshal(rec.bodies[0], &arr); err != nil || len(arr) != 2 || arr[1]["msg"] != "two" {
+		t.Errorf("unexpected body %s, %v", rec.bodies[0], err)
+	}
+}
+
+func TestHTTPRetry(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch calls.Add(1) {
+		case 1:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case 2:
+			w.WriteHeader(http.StatusTooManyRequests)
+		case 3:
+			// 成功
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer srv.Close()
+
+	var errs []error
+	h := &HTTP{
+		URL:     srv.URL,
+		Backoff: Backoff{Initial: time.Millisecond, Max: 5 * time.Millisecond},
+		OnError: func(err error, dropped int) { errs = append(errs, err) },
+	}
+	h.Write(&Message{Content: "retried"})
+	if err := h.Flush(context.Background()); err != nil || calls.Load() != 3 {
+		t.Fatalf("Flush = %v, calls = %d", err, calls.Load())
+	}
+	// 4xx 不重试
+	h.Write(&Message{Content: "rejected"})
+	if err := h.Flush(context.Background()); err == nil || calls.Load() != 4 {
+		t.Fatalf("Flush = %v, calls = %d", err, calls.Load())
+	}
+	_ = h.Close()
+	if h.Dropped() != 1 || len(errs) != 1 {
+		t.Errorf("Dropped = %d, errors = %v", h.Dropped(), errs)
+	}
+}
+
+func TestHTTPBatchBytes(t *testing.T) {
+	for _, format := range []HTTPFormat{HTTPJSONLines, HTTPJSONArray} {
+		rec := &httpRecorder{}
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			rec.record(t, req)
+		}))
+
+		// 每条日志编码后115字节，两条日志加上分隔符会超出 BatchBytes
+		var dropped atomic.Int32
+		h := &HTTP{URL: srv.URL, Format: format, BatchBytes: 230, OnError: func(err error, n int) { dropped.Add(int32(n)) }}
+		msg := func(content string) *Message {
+			return &Message{Level: LevelInfo, Time: time.Unix(0, 0).UTC(), Content: content}
+		}
+		for i := 0; i < 5; i++ {
+			h.Write(msg(strings.Repeat("a", 60)))
+		}
+		h.Write(msg(strings.Repeat("b", 400)))
+		if err := h.Flush(context.Background()); err == nil {
+			t.Fatalf("format %v: Flush should report the oversized entry", format)
+		}
+		_ = h.Close()
+		srv.Close()
+		total := 0
+		for _, body := range rec.bodies {
+			if len(body) > 230 {
+				t.Errorf("format %v: batch of %d bytes exceeds limit: %s", format, len(body), body)
+			}
+			total += strings.Count(string(body), strings.Repeat("a", 60))
+		}
+		if total != 5 || dropped.Load() != 1 || h.Dropped() != 1 {
+			t.Errorf("format %v: sent %d, dropped %d/%d", format, total, dropped.Load(), h.Dropped())
+		}
+	}
+}
+
+func TestHTTPRetryAfterMax(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if calls.Add(1) == 1 {
+			w.Header().Set("Retry-After", "3600")
+			w.WriteHeader(http.StatusTooManyRequests)
+		}
+	}))
+	defer srv.Close()
+
+	// Retry-After 超过 Backoff.Max 时按 Backoff.Max 等待
+	h := &HTTP{URL: srv.URL, Backoff: Backoff{Initial: time.Millisecond, Max: 10 * time.Millisecond}}
+	defer h.Close()
+	h.Write(&Message{Content: "retried"})
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	if err := h.Flush(ctx); err != nil || calls.Load() != 2 {
+		t.Fatalf("Flush = %v, calls = %d", err, calls.Load())
+	}
+}
@@ -13,20 +13,34 @@ import (
 
 type filePathFormatter func(string, int) string
 
-type Logger struct {
+// core 日志器的共享部分，子日志器与父日志器共用同一个core
+type core struct {
 	level             Level
+	outputLevel       Level // 所有输出中最低的输出等级
 	outputs           map[string]Output
 	callDepth         int
 	filePathFormatter filePathFormatter
+	exitFunc          func(int)     // Fatal 退出程序的方法，默认 os.Exit
+	exitTimeout       time.Duration // Fatal 退出前等待输出关闭的最长时间
 	mutex             sync.Mutex
 }
 
+const defaultExitTimeout = 5 * time.Second
+
+type Logger struct {
+	*core
+	name   string  // 日志器名称，子日志器以 . 连接，例如: game.room
+	fields []Field // 绑定的字段，每条日志都会携带
+}
+
 func New(depth ...int) *Logger {
 	dep := append(depth, 2)[0]
-	l := &Logger{}
+	l := &Logger{core: &core{}}
 	l.level = LevelError
 	l.outputs = map[string]Output{}
 	l.callDepth = dep
+	l.exitFunc = os.Exit
+	l.exitTimeout = defaultExitTimeout
 	return l
 }
 func (log *Logger) Close() error {
@@ -40,7 +54,7 @@ func (log *Logger) Close() error {
 			remainingOutputs[k] = output
 		}
 	}
-	log.outputs = remainingOutputs
+	log.setOutputs(remainingOutputs)
 	if len(errs) > 0 {
 		return fmt.Errorf("close logger error: %v", errs)
 	}
@@ -64,6 +78,12 @@ func (log *Logger) Write(msg *Message, stack ...string) {
 	if len(stack) > 0 {
 		msg.Stack = stack[0]
 	}
+	if msg.Name == "" {
+		msg.Name = log.name
+	}
+	if len(log.fields) > 0 {
+		msg.Fields = append(log.fields[:len(log.fields):len(log.fields)], msg.Fields...)
+	}
 	for _, output := range log.outputs {
 		output.Write(msg)
 	}
@@ -73,52 +93,212 @@ func (log *Logger) Sprint(level Level, content string, stack ...string) {
 	log.Write(&Message{Content: content, Level: level}, stack...)
 }
 
+// With 返回绑定了字段的子日志器，参数规则同 Fields
+// 子日志器与父日志器共用输出和日志等级，并继承父日志器的名称和字段
+func (log *Logger) With(kv ...any) *Logger {
+	fields := Fields(kv...)
+	if len(fields) == 0 {
+		return log
+	}
+	l := log.clone()
+	l.fields = append(l.fields, fields...)
+	return l
+}
+
+// Named 返回指定名称的子日志器，名称以 . 连接在父日志器名称之后
+func (log *Logger) Named(name string) *Logger {
+	if name == "" {
+		return log
+	}
+	l := log.clone()
+	if l.name != "" {
+		l.name = l.name + "." + name
+	} else {
+		l.name = name
+	}
+	return l
+}
+
+// Name 日志器名称
+func (log *Logger) Name() string {
+	return log.name
+}
+
+func (log *Logger) clone() *Logger {
+	return &Logger{core: log.core, name: log.name, fields: log.fields[:len(log.fields):len(log.fields)]}
+}
+
+// Enabled 该等级的日志是否会被输出，同时参考日志器等级和各个输出的等级
+// 可用于在生成日志内容之前判断，避免不必要的开销
+func (log *Logger) Enabled(level Level) bool {
+	return level >= log.level && level >= log.outputLevel
+}
+
+func (log *Logger) sprintw(level Level, content string, kv []any, stack ...string) {
+	log.Write(&Message{Content: content, Level: level, Fields: Fields(kv...)}, stack...)
+}
+
 func (log *Logger) Fatal(format any, args ...any) {
-	content := Format(format, args...)
-	log.Sprint(LevelFatal, content, string(debug.Stack()))
-	os.Exit(1)
+	if log.Enabled(LevelFatal) {
+		log.Sprint(LevelFatal, Format(format, args...), string(debug.Stack()))
+	}
+	log.exit(1)
 }
 
 func (log *Logger) Panic(format any, args ...any) {
 	content := Format(format, args...)
-	log.Sprint(LevelPanic, content, string(debug.Stack()))
+	if log.Enabled(LevelPanic) {
+		log.Sprint(LevelPanic, content, string(debug.Stack()))
+	}
 	panic(content)
 }
 
 // Error Log ERROR level message.
 func (log *Logger) Error(format any, args ...any) {
+	if !log.Enabled(LevelError) {
+		return
+	}
 	content := Format(format, args...)
 	log.Sprint(LevelError, content, string(debug.Stack()))
 }
 func (log *Logger) Alert(format any, args ...any) {
+	if !log.Enabled(LevelAlert) {
+		return
+	}
 	content := Format(format, args...)
 	log.Sprint(LevelAlert, content)
 }
 
 // Debug Log DEBUG level message.
 func (log *Logger) Debug(format any, args ...any) {
+	if !log.Enabled(LevelDebug) {
+		return
+	}
 	content := Format(format, args...)
 	log.Sprint(LevelDebug, content)
 }
 
 // Trace Log TRAC level message.
 func (log *Logger) Trace(format any, args ...any) {
+	if !log.Enabled(LevelTrace) {
+		return
+	}
 	content := Format(format, args...)
 	log.Sprint(LevelTrace, content)
 }
 
 // Info Log INFO level message.
 func (log *Logger) Info(format any, args ...any) {
+	if !log.Enabled(LevelInfo) {
+		return
+	}
 	content := Format(format, args...)
 	log.Sprint(LevelInfo, content)
 }
 
 // Warn Log WARN level message.
 func (log *Logger) Warn(format any, args ...any) {
+	if !log.Enabled(LevelWarn) {
+		return
+	}
 	content := Format(format, args...)
 	log.Sprint(LevelWarn, content)
 }
 
+func (log *Logger) Fatalw(msg string, kv ...any) {
+	if log.Enabled(LevelFatal) {
+		log.sprintw(LevelFatal, msg, kv, string(debug.Stack()))
+	}
+	log.exit(1)
+}
+
+func (log *Logger) Panicw(msg string, kv ...any) {
+	if log.Enabled(LevelPanic) {
+		log.sprintw(LevelPanic, msg, kv, string(debug.Stack()))
+	}
+	panic(msg)
+}
+
+// Errorw 带结构化字段的 ERROR 日志
+func (log *Logger) Errorw(msg string, kv ...any) {
+	if !log.Enabled(LevelError) {
+		return
+	}
+	log.sprintw(LevelError, msg, kv, string(debug.Stack()))
+}
+
+func (log *Logger) Alertw(msg string, kv ...any) {
+	if !log.Enabled(LevelAlert) {
+		return
+	}
+	log.sprintw(LevelAlert, msg, kv)
+}
+
+// Debugw 带结构化字段的 DEBUG 日志
+func (log *Logger) Debugw(msg string, kv ...any) {
+	if !log.Enabled(LevelDebug) {
+		return
+	}
+	log.sprintw(LevelDebug, msg, kv)
+}
+
+// Tracew 带结构化字段的 TRACE 日志
+func (log *Logger) Tracew(msg string, kv ...any) {
+	if !log.Enabled(LevelTrace) {
+		return
+	}
+	log.sprintw(LevelTrace, msg, kv)
+}
+
+// Infow 带结构化字段的 INFO 日志, 例如: Infow("login", "uid", uid, logger.Duration("cost", d))
+func (log *Logger) Infow(msg string, kv ...any) {
+	if !log.Enabled(LevelInfo) {
+		return
+	}
+	log.sprintw(LevelInfo, msg, kv)
+}
+
+// Warnw 带结构化字段的 WARN 日志
+func (log *Logger) Warnw(msg string, kv ...any) {
+	if !log.Enabled(LevelWarn) {
+		return
+	}
+	log.sprintw(LevelWarn, msg, kv)
+}
+
+// exit 关闭所有输出，确保缓冲中的日志写入后再退出程序，关闭超时后直接退出
+func (log *Logger) exit(code int) {
+	done := make(chan struct{})
+	go func() {
+		_ = log.Close()
+		close(done)
+	}()
+	timer := time.NewTimer(log.exitTimeout)
+	select {
+	case <-done:
+	case <-timer.C:
+		_, _ = fmt.Fprintf(os.Stderr, "logger close outputs timeout:%v\n", log.exitTimeout)
+	}
+	timer.Stop()
+	log.exitFunc(code)
+}
+
+// SetExitFunc 设置 Fatal 退出程序的方法，默认 os.Exit，主要用于测试
+func (log *Logger) SetExitFunc(f func(int)) {
+	if f == nil {
+		f = os.Exit
+	}
+	log.exitFunc = f
+}
+
+// SetExitTimeout 设置 Fatal 退出前等待输出关闭的最长时间，默认5秒
+func (log *Logger) SetExitTimeout(d time.Duration) {
+	if d <= 0 {
+		return
+	}
+	log.exitTimeout = d
+}
+
 // SetLevel 设置日志输出等级
 func (log *Logger) SetLevel(level Level) {
 	log.level = level
@@ -0,0 +1,228 @@
+package logger
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memoryOutput 测试用输出，保存所有收到的消息
+type memoryOutput struct {
+	mutex    sync.Mutex
+	messages []Message
+}
+
+func (m *memoryOutput) Write(msg *Message) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.messages = append(m.messages, *msg)
+}
+
+func (m *memoryOutput) Close() error {
+	return nil
+}
+
+func (m *memoryOutput) last(t *testing.T) Message {
+	t.Helper()
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if len(m.messages) == 0 {
+		t.Fatalf("no message received")
+	}
+	return m.messages[len(m.messages)-1]
+}
+
+func newMemoryLogger() (*Logger, *memoryOutput) {
+	log := New()
+	log.SetLevel(LevelDebug)
+	out := &memoryOutput{}
+	_ = log.SetOutput("memory", out)
+	return log, out
+}
+
+func TestLoggerFields(t *testing.T) {
+	log, out := newMemoryLogger()
+	log.Infow("login", "uid", 1001, Duration("cost", 15*time.Millisecond), "dangling")
+
+	msg := out.last(t)
+	if msg.Content != "login" || msg.Level != LevelInfo {
+		t.Fatalf("unexpected message: %+v", msg)
+	}
+	want := []Field{{"uid", 1001}, {"cost", 15 * time.Millisecond}, {badKey, "dangling"}}
+	if len(msg.Fields) != len(want) {
+		t.Fatalf("fields = %v, want %v", msg.Fields, want)
+	}
+	for i, f := range want {
+		if msg.Fields[i] != f {
+			t.Errorf("field %d = %v, want %v", i, msg.Fields[i], f)
+		}
+	}
+
+	msg.Path = ""
+	text := msg.Sprintf().String()
+	if !strings.HasSuffix(text, `login uid=1001 cost=15ms !BADKEY=dangling`) {
+		t.Errorf("Sprintf = %q", text)
+	}
+}
+
+func TestLoggerWith(t *testing.T) {
+	log, out := newMemoryLogger()
+	child := log.With("request", "r-1")
+	child.Warnw("slow", Err(errors.New("timeout")))
+
+	msg := out.last(t)
+	if len(msg.Fields) != 2 || msg.Fields[0].Key != "request" || msg.Fields[1].String() != "timeout" {
+		t.Fatalf("unexpected fields: %v", msg.Fields)
+	}
+	// 父日志器不受子日志器影响
+	log.Info("plain")
+	if msg = out.last(t); len(msg.Fields) != 0 {
+		t.Fatalf("parent logger carries fields: %v", msg.Fields)
+	}
+	// 字段值需要加引号
+	b := NewBuffer()
+	writeFields(b, []Field{String("name", "a b"), String("empty", "")})
+	if b.String() != ` name="a b" empty=""` {
+		t.Errorf("writeFields = %q", b.String())
+	}
+}
+
+func TestLoggerNamed(t *testing.T) {
+	log, out := newMemoryLogger()
+	room := log.Named("game").With("sid", 7).Named("room")
+	if room.Name() != "game.room" {
+		t.Fatalf("Name = %q", room.Name())
+	}
+	room.Debugw("enter", "uid", 1)
+	msg := out.last(t)
+	if msg.Name != "game.room" || len(msg.Fields) != 2 || msg.Fields[0].Key != "sid" {
+		t.Fatalf("unexpected message: %+v", msg)
+	}
+	// 子日志器共用父日志器的等级
+	log.SetLevel(LevelWarn)
+	room.Info("ignored")
+	if msg = out.last(t); msg.Content != "enter" {
+		t.Fatalf("child ignores parent level: %+v", msg)
+	}
+	msg.Path = ""
+	if text := msg.Sprintf().String(); !strings.HasSuffix(text, "game.room: enter sid=7 uid=1") {
+		t.Errorf("Sprintf = %q", text)
+	}
+}
+
+// blockOutput 关闭时阻塞的输出
+type blockOutput struct {
+	memoryOutput
+	release chan struct{}
+}
+
+func (b *blockOutput) Close() error {
+	<-b.release
+	return nil
+}
+
+func TestFatalFlush(t *testing.T) {
+	dir := t.TempDir()
+	log := New()
+	f := NewFile(dir)
+	_ = log.SetOutput("file", f)
+	code := -1
+	log.SetExitFunc(func(c int) { code = c })
+
+	log.Fatal("crash %d", 42)
+	if code != 1 {
+		t.Fatalf("exit code = %d", code)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "log.log"))
+	if err != nil || !strings.Contains(string(data), "crash 42") {
+		t.Fatalf("fatal message not flushed: %q, %v", data, err)
+	}
+}
+
+func TestFatalTimeout(t *testing.T) {
+	log := New()
+	out := &blockOutput{release: make(chan struct{})}
+	defer close(out.release)
+	_ = log.SetOutput("block", out)
+	exited := make(chan int, 1)
+	log.SetExitFunc(func(c int) { exited <- c })
+	log.SetExitTimeout(50 * time.Millisecond)
+
+	start := time.Now()
+	log.Fatalw("crash")
+	select {
+	case <-exited:
+	default:
+		t.Fatalf("exit func not called")
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("exit waited %v", d)
+	}
+	if len(out.messages) != 1 {
+		t.Errorf("fatal message not written")
+	}
+}
+
+func TestOutputLevel(t *testing.T) {
+	log := New()
+	log.SetLevel(LevelDebug)
+	all, warn, named := &memoryOutput{}, &memoryOutput{}, &memoryOutput{}
+	_ = log.SetOutput("all", all)
+	_ = log.SetOutput("warn", warn, OutputLevel(LevelWarn))
+	_ = log.SetOutput("named", named, OutputFilter(func(msg *Message) bool { return msg.Name == "db" }))
+
+	log.Debug("debug")
+	log.Warn("warn")
+	log.Named("db").Info("query")
+
+	if len(all.messages) != 3 || len(warn.messages) != 1 || len(named.messages) != 1 {
+		t.Fatalf("all=%d warn=%d named=%d", len(all.messages), len(warn.messages), len(named.messages))
+	}
+	if warn.messages[0].Content != "warn" || named.messages[0].Content != "query" {
+		t.Errorf("unexpected messages: %v %v", warn.messages, named.messages)
+	}
+}
+
+func TestLoggerDisabled(t *testing.T) {
+	log, out := newMemoryLogger()
+	log.SetLevel(LevelPanic)
+	called := false
+	lazy := Lazy(func() any { called = true; return 42 })
+
+	allocs := testing.AllocsPerRun(100, func() {
+		log.Debug("state %v", lazy)
+		log.Infow("state", "value", lazy)
+		log.Errorw("ignored")
+	})
+	if called {
+		t.Errorf("lazy argument evaluated for disabled level")
+	}
+	if allocs != 0 {
+		t.Errorf("disabled log allocates %v times", allocs)
+	}
+
+	log.SetLevel(LevelWarn)
+	log.Warn("state %03d", lazy)
+	log.Warnw("state", "value", lazy)
+	if !called || out.messages[0].Content != "state 042" || out.messages[1].Fields[0].String() != "42" {
+		t.Errorf("unexpected lazy output: %+v", out.messages)
+	}
+}
+
+func TestLoggerEnabled(t *testing.T) {
+	log := New()
+	log.SetLevel(LevelDebug)
+	_ = log.SetOutput("warn", &memoryOutput{}, OutputLevel(LevelWarn))
+	_ = log.SetOutput("info", &memoryOutput{}, OutputLevel(LevelInfo))
+	if log.Enabled(LevelTrace) || !log.Enabled(LevelInfo) {
+		t.Errorf("Enabled ignores output levels")
+	}
+	log.DelOutput("info")
+	if log.Enabled(LevelInfo) || !log.Enabled(LevelWarn) {
+		t.Errorf("Enabled not updated after DelOutput")
+	}
+}
@@ -0,0 +1,184 @@
+package logger
+
+import (
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	lokiPushPath = "/loki/api/v1/push"
+
+	LokiLabelLevel  = "level"  // 日志等级标签，值为小写的等级名称，例如 info
+	LokiLabelLogger = "logger" // 日志器名称标签
+)
+
+// NewLoki 创建 Grafana Loki 输出，url 没有路径时自动补全 /loki/api/v1/push
+func NewLoki(url string, cap ...int) *Loki {
+	if !strings.Contains(url, "/loki/api/") {
+		url = strings.TrimSuffix(url, "/") + lokiPushPath
+	}
+	l := &Loki{HTTP: NewHTTP(url, cap...)}
+	l.HTTP.marshal = l.marshal
+	return l
+}
+
+// Loki 推送到 Loki push API 的输出，批量、重试和队列由 HTTP 负责
+// 每条日志按 Labels 和 LabelKeys 生成的标签分组成 stream，日志行由 Encoder 编码，默认 JSONEncoder
+// Protobuf 为 true 时使用 snappy 压缩的 protobuf 请求体，此时不要开启 Gzip
+// 需要在首次写入前完成配置
+type Loki struct {
+	*HTTP
+	Protobuf  bool              `json:"protobuf"`  //使用 protobuf+snappy 请求体，默认JSON
+	Labels    map[string]string `json:"labels"`    //固定标签，例如 job, env
+	LabelKeys []string          `json:"labelKeys"` //动态标签，可以是 level, logger 或者字段名，默认 level 和 logger
+}
+
+// lokiStream 相同标签的日志
+type lokiStream struct {
+	key     string      //Prometheus 格式的标签，例如 {level="info"}
+	labels  [][2]string //按名称排序的标签
+	entries []*httpEntry
+}
+
+func (l *Loki) Name() string {
+	return "loki+" + l.URL
+}
+
+// labels 生成日志的标签，值为空的标签被忽略，没有任何标签时使用 job="logger"
+func (l *Loki) labels(msg *Message) [][2]string {
+	m := make(map[string]string, len(l.Labels)+2)
+	for k, v := range l.Labels {
+		if v != "" {
+			m[lokiLabelName(k)] = v
+		}
+	}
+	keys := l.LabelKeys
+	if keys == nil {
+		keys = []string{LokiLabelLevel, LokiLabelLogger}
+	}
+	for _, key := range keys {
+		var value string
+		switch key {
+		case LokiLabelLevel:
+			value = strings.ToLower(msg.Level.Name())
+		case LokiLabelLogger:
+			value = msg.Name
+		default:
+			for _, f := range msg.Fields {
+				if f.Key == key {
+					value = f.String()
+				}
+			}
+		}
+		if value != "" {
+			m[lokiLabelName(key)] = value
+		}
+	}
+	if len(m) == 0 {
+		m["job"] = "logger"
+	}
+	labels := make([][2]string, 0, len(m))
+	for k, v := range m {
+		labels = append(labels, [2]string{k, v})
+	}
+	sort.Slice(labels, func(i, j int) bool { return labels[i][0] < labels[j][0] })
+	return labels
+}
+
+// streams 按标签分组，保持日志的先后顺序
+func (l *Loki) streams(entries []*httpEntry) []*lokiStream {
+	var streams []*lokiStream
+	index := map[string]*lokiStream{}
+	for _, e := range entries {
+		labels := l.labels(&e.msg)
+		var b strings.Builder
+		b.WriteByte('{')
+		for i, label := range labels {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			b.WriteString(label[0])
+			b.WriteByte('=')
+			b.WriteString(strconv.Quote(label[1]))
+		}
+		b.WriteByte('}')
+		key := b.String()
+		s := index[key]
+		if s == nil {
+			s = &lokiStream{key: key, labels: labels}
+			index[key] = s
+			streams = append(streams, s)
+		}
+		s.entries = append(s.entries, e)
+	}
+	return streams
+}
+
+func (l *Loki) marshal(w io.Writer, entries []*httpEntry) (string, error) {
+	buf := NewBuffer()
+	defer buf.Free()
+	streams := l.streams(entries)
+	if l.Protobuf {
+		// PushRequest{streams=1: StreamAdapter{labels=1, entries=2: EntryAdapter{timestamp=1, line=2}}}
+		for _, s := range streams {
+			appendProtoMessage(buf, 1, func(m *Buffer) {
+				appendProtoString(m, 1, s.key)
+				for _, e := range s.entries {
+					appendProtoMessage(m, 2, func(m *Buffer) {
+						appendProtoMessage(m, 1, func(m *Buffer) {
+							appendProtoVarint(m, 1, uint64(e.msg.Time.Unix()))
+							appendProtoVarint(m, 2, uint64(e.msg.Time.Nanosecond()))
+						})
+						appendProtoBytes(m, 2, e.data.B)
+					})
+				}
+			})
+		}
+		_, err := w.Write(snappyEncode(nil, buf.B))
+		return "application/x-protobuf", err
+	}
+	// {"streams":[{"stream":{"k":"v"},"values":[["<ns>","line"]]}]}
+	buf.WriteString(`{"streams":[`)
+	for i, s := range streams {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		buf.WriteString(`{"stream":{`)
+		for j, label := range s.labels {
+			if j > 0 {
+				buf.WriteByte(',')
+			}
+			appendJSONString(buf, label[0])
+			buf.WriteByte(':')
+			appendJSONString(buf, label[1])
+		}
+		buf.WriteString(`},"values":[`)
+		for j, e := range s.entries {
+			if j > 0 {
+				buf.WriteByte(',')
+			}
+			buf.WriteString(`["`)
+			buf.AppendInt(e.msg.Time.UnixNano())
+			buf.WriteString(`",`)
+			appendJSONString(buf, string(e.data.B))
+			buf.WriteByte(']')
+		}
+		buf.WriteString(`]}`)
+	}
+	buf.WriteString(`]}`)
+	_, err := w.Write(buf.B)
+	return "application/json", err
+}
+
+// lokiLabelName 标签名只能包含字母、数字和下划线，并且不能以数字开头
+func lokiLabelName(key string) string {
+	b := []byte(key)
+	for i, c := range b {
+		if c != '_' && (c < 'a' || c > 'z') && (c < 'A' || c > 'Z') && (i == 0 || c < '0' || c > '9') {
+			b[i] = '_'
+		}
+	}
+	return string(b)
+}
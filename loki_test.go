@@ -0,0 +1,221 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLokiJSON(t *testing.T) {
+	rec := &httpRecorder{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != lokiPushPath || req.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("unexpected request %v %v", req.URL.Path, req.Header)
+		}
+		rec.record(t, req)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	l := NewLoki(srv.URL + "/")
+	l.Labels = map[string]string{"job": "game"}
+	l.LabelKeys = []string{LokiLabelLevel, LokiLabelLogger, "zone.id"}
+	now := time.Unix(1700000000, 123456789)
+	l.Write(&Message{Name: "room", Level: LevelInfo, Time: now, Content: "a", Fields: []Field{Int("zone.id", 1)}})
+	l.Write(&Message{Name: "room", Level: LevelError, Time: now, Content: "b", Fields: []Field{Int("zone.id", 1)}})
+	l.Write(&Message{Name: "room", Level: LevelInfo, Time: now.Add(time.Nanosecond), Content: "c\n", Fields: []Field{Int("zone.id", 1)}})
+	if err := l.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	if rec.count() != 1 {
+		t.Fatalf("expected 1 request, got %d", rec.count())
+	}
+	var body struct {
+		Streams []struct {
+			Stream map[string]string `json:"stream"`
+			Values [][2]string       `json:"values"`
+		} `json:"streams"`
+	}
+	if err := json.Unmarshal(rec.bodies[0], &body); err != nil {
+		t.Fatalf("unmarshal %s: %v", rec.bodies[0], err)
+	}
+	if len(body.Streams) != 2 {
+		t.Fatalf("expected 2 streams: %s", rec.bodies[0])
+	}
+	info := body.Streams[0]
+	want := map[string]string{"job": "game", "level": "info", "logger": "room", "zone_id": "1"}
+	if fmt.Sprint(info.Stream) != fmt.Sprint(want) {
+		t.Fatalf("unexpected labels %v", info.Stream)
+	}
+	if len(info.Values) != 2 || info.Values[0][0] != "1700000000123456789" || info.Values[1][0] != "1700000000123456790" {
+		t.Fatalf("unexpected values %v", info.Values)
+	}
+	var line map[string]any
+	if err := json.Unmarshal([]byte(info.Values[1][1]), &line); err != nil || line["msg"] != "c\n" {
+		t.Fatalf("unexpected line %q: %v", info.Values[1][1], err)
+	}
+	if body.Streams[1].Stream["level"] != "error" || len(body.Streams[1].Values) != 1 {
+		t.Fatalf("unexpected error stream %v", body.Streams[1])
+	}
+	_ = l.Close()
+}
+
+func TestLokiProtobuf(t *testing.T) {
+	rec := &httpRecorder{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Header.Get("Content-Type") != "application/x-protobuf" {
+			t.Errorf("unexpected content type %v", req.Header.Get("Content-Type"))
+		}
+		rec.record(t, req)
+	}))
+	defer srv.Close()
+
+	l := NewLoki(srv.URL + lokiPushPath)
+	l.Protobuf = true
+	l.Encoder = &ContentEncoder{}
+	now := time.Unix(1700000000, 5)
+	for i := 0; i < 3; i++ {
+		l.Write(&Message{Level: LevelWarn, Time: now, Content: "repeat repeat repeat repeat " + strconv.Itoa(i)})
+	}
+	if err := l.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if rec.count() != 1 {
+		t.Fatalf("expected 1 request, got %d", rec.count())
+	}
+	data, err := snappyDecode(rec.bodies[0])
+	if err != nil {
+		t.Fatalf("snappy: %v", err)
+	}
+	streams := decodeProto(t, data)[1]
+	if len(streams) != 1 {
+		t.Fatalf("expected 1 stream, got %d", len(streams))
+	}
+	stream := decodeProto(t, streams[0])
+	if labels := string(stream[1][0]); labels != `{level="warn"}` {
+		t.Fatalf("unexpected labels %s", labels)
+	}
+	if len(stream[2]) != 3 {
+		t.Fatalf("expected 3 entries, got %d", len(stream[2]))
+	}
+	for i, b := range stream[2] {
+		entry := decodeProto(t, b)
+		ts := decodeProto(t, entry[1][0])
+		if sec, _ := binary.Uvarint(ts[1][0]); sec != 1700000000 {
+			t.Fatalf("unexpected seconds %d", sec)
+		}
+		if nanos, _ := binary.Uvarint(ts[2][0]); nanos != 5 {
+			t.Fatalf("unexpected nanos %d", nanos)
+		}
+		if line := string(entry[2][0]); line != "repeat repeat repeat repeat "+strconv.Itoa(i) {
+			t.Fatalf("unexpected line %q", line)
+		}
+	}
+}
+
+func TestSnappyEncode(t *testing.T) {
+	var src []byte
+	for i := 0; len(src) < 200000; i++ {
+		src = fmt.Appendf(src, `{"level":"INFO","msg":"request","id":%d}`+"\n", i)
+	}
+	for _, p := range [][]byte{nil, []byte("short"), src, src[:snappyBlockSize+7]} {
+		enc := snappyEncode(nil, p)
+		dec, err := snappyDecode(enc)
+		if err != nil || !bytes.Equal(dec, p) {
+			t.Fatalf("round trip %d bytes failed: %v", len(p), err)
+		}
+	}
+	if enc := snappyEncode(nil, src); len(enc) > len(src)/2 {
+		t.Fatalf("poor compression %d/%d", len(enc), len(src))
+	}
+}
+
+// snappyDecode 测试用的 snappy 块格式解压
+func snappyDecode(src []byte) ([]byte, error) {
+	n, i := binary.Uvarint(src)
+	if i <= 0 {
+		return nil, io.ErrUnexpectedEOF
+	}
+	src = src[i:]
+	dst := make([]byte, 0, n)
+	for len(src) > 0 {
+		tag := src[0]
+		switch tag & 3 {
+		case 0:
+			length := int(tag>>2) + 1
+			src = src[1:]
+			if length > 60 {
+				size := length - 60
+				length = 1
+				for j := 0; j < size; j++ {
+					length += int(src[j]) << (8 * j)
+				}
+				src = src[size:]
+			}
+			dst = append(dst, src[:length]...)
+			src = src[length:]
+		case 2:
+			length := int(tag>>2) + 1
+			offset := int(binary.LittleEndian.Uint16(src[1:]))
+			src = src[3:]
+			if offset == 0 || offset > len(dst) {
+				return nil, fmt.Errorf("invalid offset %d", offset)
+			}
+			for j := 0; j < length; j++ {
+				dst = append(dst, dst[len(dst)-offset])
+			}
+		default:
+			return nil, fmt.Errorf("unsupported tag %x", tag)
+		}
+	}
+	if uint64(len(dst)) != n {
+		return nil, fmt.Errorf("length %d, want %d", len(dst), n)
+	}
+	return dst, nil
+}
+
+// decodeProto 测试用的 protobuf 解码，按字段号返回原始值，varint 字段返回其编码
+func decodeProto(t *testing.T, p []byte) map[int][][]byte {
+	t.Helper()
+	m := map[int][][]byte{}
+	for len(p) > 0 {
+		tag, n := binary.Uvarint(p)
+		p = p[n:]
+		var v []byte
+		switch tag & 7 {
+		case protoVarint:
+			_, n = binary.Uvarint(p)
+			v, p = p[:n], p[n:]
+		case protoFixed64:
+			v, p = p[:8], p[8:]
+		case protoBytes:
+			size, n := binary.Uvarint(p)
+			v, p = p[n:n+int(size)], p[n+int(size):]
+		default:
+			t.Fatalf("unexpected wire type %d", tag&7)
+		}
+		m[int(tag>>3)] = append(m[int(tag>>3)], v)
+	}
+	return m
+}
+
+func TestLokiURL(t *testing.T) {
+	for url, want := range map[string]string{
+		"http://loki:3100":                    "http://loki:3100" + lokiPushPath,
+		"http://loki:3100/":                   "http://loki:3100" + lokiPushPath,
+		"http://gw/loki/api/v1/push?tenant=a": "http://gw/loki/api/v1/push?tenant=a",
+	} {
+		if l := NewLoki(url); l.URL != want || !strings.HasPrefix(l.Name(), "loki+") {
+			t.Fatalf("NewLoki(%q) = %q", url, l.URL)
+		}
+	}
+}
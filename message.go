@@ -8,28 +8,31 @@ import (
 const defaultTimeLayout = "2006-01-02 15:04:05-0700" // 日志输出默认格式
 
 type Message struct {
+	Name    string // 日志器名称
 	Path    string
 	Time    time.Time
 	Level   Level
 	Stack   string
 	Content string
+	Fields  []Field // 结构化字段
 }
 
+// Sprintf 使用 TextEncoder 格式化日志
 func (this *Message) Sprintf() *strings.Builder {
+	buf := NewBuffer()
+	defer buf.Free()
+	_ = defaultTextEncoder.Encode(buf, this)
 	b := strings.Builder{}
-	b.WriteString(this.Time.Format(defaultTimeLayout))
-	b.WriteString(" [")
-	b.WriteString(this.Level.String())
-	b.WriteString("] ")
-	if this.Path != "" {
-		b.WriteString("[")
-		b.WriteString(this.Path)
-		b.WriteString("] ")
-	}
-	if this.Stack != "" {
-		b.WriteString("\n")
-		b.WriteString(this.Stack)
-	}
-	b.WriteString(this.Content)
+	b.Write(buf.Bytes())
 	return &b
 }
+
+// writeContent 输出日志器名称，正文和结构化字段
+func (this *Message) writeContent(buf *Buffer) {
+	if this.Name != "" {
+		buf.WriteString(this.Name)
+		buf.WriteString(": ")
+	}
+	buf.WriteString(this.Content)
+	writeFields(buf, this.Fields)
+}
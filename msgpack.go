@@ -0,0 +1,252 @@
+package logger
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+// MessagePack 编码，只实现日志输出需要的类型
+
+// msgpackMaxReadLength 读取应答时字符串长度和map元素数量的上限，应答只是很短的map
+const msgpackMaxReadLength = 4096
+
+func appendMsgpackArrayHeader(buf *Buffer, n int) {
+	switch {
+	case n < 16:
+		buf.WriteByte(0x90 | byte(n))
+	case n <= math.MaxUint16:
+		buf.WriteByte(0xdc)
+		buf.B = binary.BigEndian.AppendUint16(buf.B, uint16(n))
+	default:
+		buf.WriteByte(0xdd)
+		buf.B = binary.BigEndian.AppendUint32(buf.B, uint32(n))
+	}
+}
+
+func appendMsgpackMapHeader(buf *Buffer, n int) {
+	switch {
+	case n < 16:
+		buf.WriteByte(0x80 | byte(n))
+	case n <= math.MaxUint16:
+		buf.WriteByte(0xde)
+		buf.B = binary.BigEndian.AppendUint16(buf.B, uint16(n))
+	default:
+		buf.WriteByte(0xdf)
+		buf.B = binary.BigEndian.AppendUint32(buf.B, uint32(n))
+	}
+}
+
+func appendMsgpackString(buf *Buffer, s string) {
+	n := len(s)
+	switch {
+	case n < 32:
+		buf.WriteByte(0xa0 | byte(n))
+	case n <= math.MaxUint8:
+		buf.WriteByte(0xd9)
+		buf.WriteByte(byte(n))
+	case n <= math.MaxUint16:
+		buf.WriteByte(0xda)
+		buf.B = binary.BigEndian.AppendUint16(buf.B, uint16(n))
+	default:
+		buf.WriteByte(0xdb)
+		buf.B = binary.BigEndian.AppendUint32(buf.B, uint32(n))
+	}
+	buf.WriteString(s)
+}
+
+func appendMsgpackBinary(buf *Buffer, p []byte) {
+	n := len(p)
+	switch {
+	case n <= math.MaxUint8:
+		buf.WriteByte(0xc4)
+		buf.WriteByte(byte(n))
+	case n <= math.MaxUint16:
+		buf.WriteByte(0xc5)
+		buf.B = binary.BigEndian.AppendUint16(buf.B, uint16(n))
+	default:
+		buf.WriteByte(0xc6)
+		buf.B = binary.BigEndian.AppendUint32(buf.B, uint32(n))
+	}
+	buf.Write(p)
+}
+
+func appendMsgpackInt(buf *Buffer, i int64) {
+	switch {
+	case i >= 0:
+		appendMsgpackUint(buf, uint64(i))
+	case i >= -32:
+		buf.WriteByte(byte(i))
+	case i >= math.MinInt8:
+		buf.WriteByte(0xd0)
+		buf.WriteByte(byte(i))
+	case i >= math.MinInt16:
+		buf.WriteByte(0xd1)
+		buf.B = binary.BigEndian.AppendUint16(buf.B, uint16(i))
+	case i >= math.MinInt32:
+		buf.WriteByte(0xd2)
+		buf.B = binary.BigEndian.AppendUint32(buf.B, uint32(i))
+	default:
+		buf.WriteByte(0xd3)
+		buf.B = binary.BigEndian.AppendUint64(buf.B, uint64(i))
+	}
+}
+
+func appendMsgpackUint(buf *Buffer, u uint64) {
+	switch {
+	case u < 128:
+		buf.WriteByte(byte(u))
+	case u <= math.MaxUint8:
+		buf.WriteByte(0xcc)
+		buf.WriteByte(byte(u))
+	case u <= math.MaxUint16:
+		buf.WriteByte(0xcd)
+		buf.B = binary.BigEndian.AppendUint16(buf.B, uint16(u))
+	case u <= math.MaxUint32:
+		buf.WriteByte(0xce)
+		buf.B = binary.BigEndian.AppendUint32(buf.B, uint32(u))
+	default:
+		buf.WriteByte(0xcf)
+		buf.B = binary.BigEndian.AppendUint64(buf.B, u)
+	}
+}
+
+// appendMsgpackEventTime Fluentd EventTime 扩展类型(fixext8, type 0)
+func appendMsgpackEventTime(buf *Buffer, t time.Time) {
+	buf.WriteByte(0xd7)
+	buf.WriteByte(0x00)
+	buf.B = binary.BigEndian.AppendUint32(buf.B, uint32(t.Unix()))
+	buf.B = binary.BigEndian.AppendUint32(buf.B, uint32(t.Nanosecond()))
+}
+
+// appendMsgpackValue 按类型编码字段值，无法识别的类型使用其文本形式
+func appendMsgpackValue(buf *Buffer, value any) {
+	switch v := value.(type) {
+	case Lazy:
+		appendMsgpackValue(buf, v())
+	case nil:
+		buf.WriteByte(0xc0)
+	case string:
+		appendMsgpackString(buf, v)
+	case bool:
+		if v {
+			buf.WriteByte(0xc3)
+		} else {
+			buf.WriteByte(0xc2)
+		}
+	case int:
+		appendMsgpackInt(buf, int64(v))
+	case int8:
+		appendMsgpackInt(buf, int64(v))
+	case int16:
+		appendMsgpackInt(buf, int64(v))
+	case int32:
+		appendMsgpackInt(buf, int64(v))
+	case int64:
+		appendMsgpackInt(buf, v)
+	case uint:
+		appendMsgpackUint(buf, uint64(v))
+	case uint8:
+		appendMsgpackUint(buf, uint64(v))
+	case uint16:
+		appendMsgpackUint(buf, uint64(v))
+	case uint32:
+		appendMsgpackUint(buf, uint64(v))
+	case uint64:
+		appendMsgpackUint(buf, v)
+	case float32:
+		buf.WriteByte(0xca)
+		buf.B = binary.BigEndian.AppendUint32(buf.B, math.Float32bits(v))
+	case float64:
+		buf.WriteByte(0xcb)
+		buf.B = binary.BigEndian.AppendUint64(buf.B, math.Float64bits(v))
+	case []byte:
+		appendMsgpackBinary(buf, v)
+	default:
+		appendMsgpackString(buf, Field{Value: value}.String())
+	}
+}
+
+// readMsgpackStringMap 读取键和值都是字符串的map，用于读取服务器的应答
+func readMsgpackStringMap(r io.Reader) (map[string]string, error) {
+	b, err := readMsgpackBytes(r, 1)
+	if err != nil {
+		return nil, err
+	}
+	var n int
+	switch c := b[0]; {
+	case c&0xf0 == 0x80:
+		n = int(c & 0x0f)
+	case c == 0xde:
+		if b, err = readMsgpackBytes(r, 2); err != nil {
+			return nil, err
+		}
+		n = int(binary.BigEndian.Uint16(b))
+	case c == 0xdf:
+		if b, err = readMsgpackBytes(r, 4); err != nil {
+			return nil, err
+		}
+		n = int(binary.BigEndian.Uint32(b))
+	default:
+		return nil, fmt.Errorf("msgpack: unexpected map type 0x%x", c)
+	}
+	if n > msgpackMaxReadLength {
+		return nil, fmt.Errorf("msgpack: map too large: %d", n)
+	}
+	m := make(map[string]string, n)
+	for i := 0; i < n; i++ {
+		k, err := readMsgpackString(r)
+		if err != nil {
+			return nil, err
+		}
+		if m[k], err = readMsgpackString(r); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+func readMsgpackString(r io.Reader) (string, error) {
+	b, err := readMsgpackBytes(r, 1)
+	if err != nil {
+		return "", err
+	}
+	var n int
+	switch c := b[0]; {
+	case c&0xe0 == 0xa0:
+		n = int(c & 0x1f)
+	case c == 0xd9 || c == 0xc4:
+		if b, err = readMsgpackBytes(r, 1); err != nil {
+			return "", err
+		}
+		n = int(b[0])
+	case c == 0xda || c == 0xc5:
+		if b, err = readMsgpackBytes(r, 2); err != nil {
+			return "", err
+		}
+		n = int(binary.BigEndian.Uint16(b))
+	case c == 0xdb || c == 0xc6:
+		if b, err = readMsgpackBytes(r, 4); err != nil {
+			return "", err
+		}
+		n = int(binary.BigEndian.Uint32(b))
+	default:
+		return "", errors.New("msgpack: expected string")
+	}
+	if n > msgpackMaxReadLength {
+		return "", fmt.Errorf("msgpack: string too long: %d", n)
+	}
+	if b, err = readMsgpackBytes(r, n); err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+func readMsgpackBytes(r io.Reader, n int) ([]byte, error) {
+	b := make([]byte, n)
+	_, err := io.ReadFull(r, b)
+	return b, err
+}
@@ -0,0 +1,363 @@
+package logger
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	otlpLogsPath       = "/v1/logs"
+	defaultOTLPTraceID = "trace_id"
+	defaultOTLPSpanID  = "span_id"
+)
+
+// otlpSeverity 日志等级对应的 OpenTelemetry SeverityNumber，Trace 高于 Debug，与 slogLevels 一致
+var otlpSeverity = map[Level]int32{
+	LevelDebug: 5,  // DEBUG
+	LevelTrace: 6,  // DEBUG2
+	LevelInfo:  9,  // INFO
+	LevelWarn:  13, // WARN
+	LevelAlert: 14, // WARN2
+	LevelError: 17, // ERROR
+	LevelPanic: 21, // FATAL
+	LevelFatal: 24, // FATAL4
+}
+
+// OTLPSeverity 日志等级对应的 OpenTelemetry SeverityNumber
+func OTLPSeverity(l Level) int32 {
+	if s, ok := otlpSeverity[l]; ok {
+		return s
+	}
+	return 9
+}
+
+// NewOTLP 创建 OTLP/HTTP 日志导出，endpoint 没有以 /v1/logs 结尾时自动补全，例如: http://collector:4318
+func NewOTLP(endpoint string, cap ...int) *OTLP {
+	if !strings.HasSuffix(endpoint, otlpLogsPath) {
+		endpoint = strings.TrimSuffix(endpoint, "/") + otlpLogsPath
+	}
+	o := &OTLP{HTTP: NewHTTP(endpoint, cap...)}
+	o.HTTP.encode = o.encode
+	o.HTTP.marshal = o.marshal
+	return o
+}
+
+// OTLP 以 OpenTelemetry LogRecord 格式导出日志，批量、重试和队列由 HTTP 负责，HTTP 的 Encoder 和 Format 不起作用
+// 日志器名称作为 InstrumentationScope，正文作为 body，字段作为 attributes，
+// TraceKey 和 SpanKey 对应的字段是合法的ID时作为 trace_id 和 span_id，否则仍然作为 attributes
+// 需要在首次写入前完成配置
+type OTLP struct {
+	*HTTP
+	Protobuf    bool              `json:"protobuf"`    //使用 protobuf 请求体，默认JSON
+	ServiceName string            `json:"serviceName"` //资源属性 service.name
+	Resource    map[string]string `json:"resource"`    //其他资源属性，例如 deployment.environment
+	TraceKey    string            `json:"traceKey"`    //trace_id 字段名，默认 trace_id，值为16字节或者32位十六进制字符串
+	SpanKey     string            `json:"spanKey"`     //span_id 字段名，默认 span_id，值为8字节或者16位十六进制字符串
+}
+
+func (o *OTLP) Name() string {
+	return "otlp+" + o.URL
+}
+
+// record 从日志中提取 LogRecord 的 trace_id, span_id 和 attributes
+func (o *OTLP) record(msg *Message) (traceID, spanID []byte, attrs []Field) {
+	traceKey, spanKey := o.TraceKey, o.SpanKey
+	if traceKey == "" {
+		traceKey = defaultOTLPTraceID
+	}
+	if spanKey == "" {
+		spanKey = defaultOTLPSpanID
+	}
+	if msg.Path != "" {
+		file, line := msg.Path, ""
+		if i := strings.LastIndexByte(msg.Path, ':'); i > 0 {
+			file, line = msg.Path[:i], msg.Path[i+1:]
+		}
+		attrs = append(attrs, String("code.file.path", file))
+		if n, err := strconv.Atoi(line); err == nil {
+			attrs = append(attrs, Int("code.line.number", n))
+		}
+	}
+	for _, f := range msg.Fields {
+		if f.Key == traceKey && traceID == nil {
+			if traceID = otlpID(f.Value, 16); traceID != nil {
+				continue
+			}
+		}
+		if f.Key == spanKey && spanID == nil {
+			if spanID = otlpID(f.Value, 8); spanID != nil {
+				continue
+			}
+		}
+		attrs = append(attrs, f)
+	}
+	if msg.Stack != "" {
+		attrs = append(attrs, String("exception.stacktrace", msg.Stack))
+	}
+	return
+}
+
+// encode 在写入日志的协程中编码 LogRecord，避免发送协程读取字段
+func (o *OTLP) encode(buf *Buffer, msg *Message) {
+	traceID, spanID, attrs := o.record(msg)
+	observed := time.Now()
+	if o.Protobuf {
+		appendProtoFixed64(buf, 1, uint64(msg.Time.UnixNano()))
+		appendProtoVarint(buf, 2, uint64(OTLPSeverity(msg.Level)))
+		appendProtoString(buf, 3, msg.Level.Name())
+		appendProtoMessage(buf, 5, func(m *Buffer) { appendOTLPProtoValue(m, msg.Content) })
+		for _, f := range attrs {
+			appendOTLPProtoAttribute(buf, 6, f.Key, f.Value)
+		}
+		appendProtoBytes(buf, 9, traceID)
+		appendProtoBytes(buf, 10, spanID)
+		appendProtoFixed64(buf, 11, uint64(observed.UnixNano()))
+		return
+	}
+	buf.WriteString(`{"timeUnixNano":"`)
+	buf.AppendInt(msg.Time.UnixNano())
+	buf.WriteString(`","observedTimeUnixNano":"`)
+	buf.AppendInt(observed.UnixNano())
+	buf.WriteString(`","severityNumber":`)
+	buf.AppendInt(int64(OTLPSeverity(msg.Level)))
+	buf.WriteString(`,"severityText":"`)
+	buf.WriteString(msg.Level.Name())
+	buf.WriteString(`","body":`)
+	appendOTLPJSONValue(buf, msg.Content)
+	if len(attrs) > 0 {
+		buf.WriteString(`,"attributes":`)
+		appendOTLPJSONAttributes(buf, attrs)
+	}
+	if traceID != nil {
+		buf.WriteString(`,"traceId":"`)
+		buf.B = hex.AppendEncode(buf.B, traceID)
+		buf.WriteString(`"`)
+	}
+	if spanID != nil {
+		buf.WriteString(`,"spanId":"`)
+		buf.B = hex.AppendEncode(buf.B, spanID)
+		buf.WriteString(`"`)
+	}
+	buf.WriteString("}")
+}
+
+func (o *OTLP) resource() []Field {
+	var attrs []Field
+	if o.ServiceName != "" {
+		attrs = append(attrs, String("service.name", o.ServiceName))
+	}
+	for k, v := range o.Resource {
+		attrs = append(attrs, String(k, v))
+	}
+	return attrs
+}
+
+// scopes 按日志器名称分组，保持日志的先后顺序
+func (o *OTLP) scopes(entries []*httpEntry) (names []string, groups map[string][]*httpEntry) {
+	groups = map[string][]*httpEntry{}
+	for _, e := range entries {
+		if _, ok := groups[e.msg.Name]; !ok {
+			names = append(names, e.msg.Name)
+		}
+		groups[e.msg.Name] = append(groups[e.msg.Name], e)
+	}
+	return
+}
+
+// marshal 生成 ExportLogsServiceRequest，所有日志属于同一个 Resource
+func (o *OTLP) marshal(w io.Writer, entries []*httpEntry) (string, error) {
+	buf := NewBuffer()
+	defer buf.Free()
+	names, groups := o.scopes(entries)
+	resource := o.resource()
+	if o.Protobuf {
+		// ExportLogsServiceRequest{resource_logs=1: ResourceLogs{resource=1, scope_logs=2: ScopeLogs{scope=1, log_records=2}}}
+		appendProtoMessage(buf, 1, func(m *Buffer) {
+			appendProtoMessage(m, 1, func(m *Buffer) {
+				for _, f := range resource {
+					appendOTLPProtoAttribute(m, 1, f.Key, f.Value)
+				}
+			})
+			for _, name := range names {
+				appendProtoMessage(m, 2, func(m *Buffer) {
+					appendProtoMessage(m, 1, func(m *Buffer) { appendProtoString(m, 1, name) })
+					for _, e := range groups[name] {
+						appendProtoBytes(m, 2, e.data.B)
+					}
+				})
+			}
+		})
+		_, err := w.Write(buf.B)
+		return "application/x-protobuf", err
+	}
+	buf.WriteString(`{"resourceLogs":[{"resource":{"attributes":`)
+	appendOTLPJSONAttributes(buf, resource)
+	buf.WriteString(`},"scopeLogs":[`)
+	for i, name := range names {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		buf.WriteString(`{"scope":{"name":`)
+		appendJSONString(buf, name)
+		buf.WriteString(`},"logRecords":[`)
+		for j, e := range groups[name] {
+			if j > 0 {
+				buf.WriteByte(',')
+			}
+			buf.Write(e.data.B)
+		}
+		buf.WriteString(`]}`)
+	}
+	buf.WriteString(`]}]}`)
+	_, err := w.Write(buf.B)
+	return "application/json", err
+}
+
+// otlpID 解析 trace_id 或 span_id，不是合法的ID时返回nil
+func otlpID(value any, size int) []byte {
+	if f, ok := value.(Lazy); ok {
+		value = f()
+	}
+	var id []byte
+	switch v := value.(type) {
+	case []byte:
+		id = v
+	case [16]byte:
+		id = v[:]
+	case [8]byte:
+		id = v[:]
+	default:
+		s := Field{Value: value}.String()
+		if len(s) != size*2 {
+			return nil
+		}
+		var err error
+		if id, err = hex.DecodeString(s); err != nil {
+			return nil
+		}
+	}
+	if len(id) != size {
+		return nil
+	}
+	for _, c := range id {
+		if c != 0 {
+			return id
+		}
+	}
+	return nil
+}
+
+// appendOTLPProtoAttribute 编码 KeyValue{key=1, value=2}
+func appendOTLPProtoAttribute(buf *Buffer, field int, key string, value any) {
+	appendProtoMessage(buf, field, func(m *Buffer) {
+		appendProtoString(m, 1, key)
+		appendProtoMessage(m, 2, func(m *Buffer) { appendOTLPProtoValue(m, value) })
+	})
+}
+
+// appendOTLPProtoValue 编码 AnyValue，oneof 字段即使是零值也需要写入
+func appendOTLPProtoValue(buf *Buffer, value any) {
+	switch v := otlpValue(value).(type) {
+	case bool:
+		appendProtoTag(buf, 2, protoVarint)
+		if v {
+			buf.WriteByte(1)
+		} else {
+			buf.WriteByte(0)
+		}
+	case int64:
+		appendProtoTag(buf, 3, protoVarint)
+		buf.B = binary.AppendUvarint(buf.B, uint64(v))
+	case float64:
+		appendProtoTag(buf, 4, protoFixed64)
+		buf.B = binary.LittleEndian.AppendUint64(buf.B, math.Float64bits(v))
+	case []byte:
+		appendProtoTag(buf, 7, protoBytes)
+		buf.B = binary.AppendUvarint(buf.B, uint64(len(v)))
+		buf.Write(v)
+	case string:
+		appendProtoTag(buf, 1, protoBytes)
+		buf.B = binary.AppendUvarint(buf.B, uint64(len(v)))
+		buf.WriteString(v)
+	}
+}
+
+func appendOTLPJSONAttributes(buf *Buffer, attrs []Field) {
+	buf.WriteByte('[')
+	for i, f := range attrs {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		buf.WriteString(`{"key":`)
+		appendJSONString(buf, f.Key)
+		buf.WriteString(`,"value":`)
+		appendOTLPJSONValue(buf, f.Value)
+		buf.WriteByte('}')
+	}
+	buf.WriteByte(']')
+}
+
+// appendOTLPJSONValue 编码 AnyValue，按 OTLP/JSON 规则 int64 使用字符串，bytes 使用base64
+func appendOTLPJSONValue(buf *Buffer, value any) {
+	switch v := otlpValue(value).(type) {
+	case bool:
+		buf.WriteString(`{"boolValue":`)
+		buf.B = strconv.AppendBool(buf.B, v)
+	case int64:
+		buf.WriteString(`{"intValue":"`)
+		buf.AppendInt(v)
+		buf.WriteByte('"')
+	case float64:
+		buf.WriteString(`{"doubleValue":`)
+		appendJSONFloat(buf, v, 64)
+	case []byte:
+		buf.WriteString(`{"bytesValue":"`)
+		buf.B = base64.StdEncoding.AppendEncode(buf.B, v)
+		buf.WriteByte('"')
+	case string:
+		buf.WriteString(`{"stringValue":`)
+		appendJSONString(buf, v)
+	}
+	buf.WriteByte('}')
+}
+
+// otlpValue 将字段值转换为 AnyValue 支持的类型: bool, int64, float64, []byte, string
+func otlpValue(value any) any {
+	switch v := value.(type) {
+	case Lazy:
+		return otlpValue(v())
+	case bool, int64, float64, []byte, string:
+		return v
+	case int:
+		return int64(v)
+	case int8:
+		return int64(v)
+	case int16:
+		return int64(v)
+	case int32:
+		return int64(v)
+	case uint:
+		return otlpValue(uint64(v))
+	case uint8:
+		return int64(v)
+	case uint16:
+		return int64(v)
+	case uint32:
+		return int64(v)
+	case uint64:
+		if v > math.MaxInt64 {
+			return strconv.FormatUint(v, 10)
+		}
+		return int64(v)
+	case float32:
+		return float64(v)
+	default:
+		return Field{Value: value}.String()
+	}
+}
@@ -0,0 +1,158 @@
+package logger
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestOTLPSeverity(t *testing.T) {
+	for level := LevelDebug; level < LevelFatal; level++ {
+		if OTLPSeverity(level) >= OTLPSeverity(level+1) {
+			t.Errorf("severity of %v (%d) not below %v (%d)", level, OTLPSeverity(level), level+1, OTLPSeverity(level+1))
+		}
+	}
+}
+
+func TestOTLPJSON(t *testing.T) {
+	rec := &httpRecorder{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != otlpLogsPath || req.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("unexpected request %v %v", req.URL.Path, req.Header)
+		}
+		rec.record(t, req)
+	}))
+	defer srv.Close()
+
+	o := NewOTLP(srv.URL)
+	o.ServiceName = "game"
+	o.Gzip = true
+	now := time.Unix(1700000000, 42)
+	o.Write(&Message{Name: "room", Path: "game/room.go:12", Level: LevelWarn, Time: now, Content: "full",
+		Fields: Fields("trace_id", "4bf92f3577b34da6a3ce929d0e0e4736", "span_id", "00f067aa0ba902b7", "uid", 7, "ok", false, "rate", 0.5)})
+	o.Write(&Message{Level: LevelError, Time: now, Content: "bad ids", Stack: "stack", Fields: Fields("trace_id", "xyz")})
+	if err := o.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	if rec.count() != 1 {
+		t.Fatalf("expected 1 request, got %d", rec.count())
+	}
+	type value struct {
+		StringValue *string  `json:"stringValue"`
+		IntValue    *string  `json:"intValue"`
+		BoolValue   *bool    `json:"boolValue"`
+		DoubleValue *float64 `json:"doubleValue"`
+	}
+	type attribute struct {
+		Key   string `json:"key"`
+		Value value  `json:"value"`
+	}
+	var body struct {
+		ResourceLogs []struct {
+			Resource struct {
+				Attributes []attribute `json:"attributes"`
+			} `json:"resource"`
+			ScopeLogs []struct {
+				Scope struct {
+					Name string `json:"name"`
+				} `json:"scope"`
+				LogRecords []struct {
+					TimeUnixNano   string      `json:"timeUnixNano"`
+					SeverityNumber int         `json:"severityNumber"`
+					SeverityText   string      `json:"severityText"`
+					Body           value       `json:"body"`
+					Attributes     []attribute `json:"attributes"`
+					TraceID        string      `json:"traceId"`
+					SpanID         string      `json:"spanId"`
+				} `json:"logRecords"`
+			} `json:"scopeLogs"`
+		} `json:"resourceLogs"`
+	}
+	if err := json.Unmarshal(rec.bodies[0], &body); err != nil {
+		t.Fatalf("unmarshal %s: %v", rec.bodies[0], err)
+	}
+	rl := body.ResourceLogs[0]
+	if a := rl.Resource.Attributes; len(a) != 1 || a[0].Key != "service.name" || *a[0].Value.StringValue != "game" {
+		t.Fatalf("unexpected resource %+v", a)
+	}
+	if len(rl.ScopeLogs) != 2 || rl.ScopeLogs[0].Scope.Name != "room" || rl.ScopeLogs[1].Scope.Name != "" {
+		t.Fatalf("unexpected scopes %s", rec.bodies[0])
+	}
+	r := rl.ScopeLogs[0].LogRecords[0]
+	if r.TimeUnixNano != "1700000000000000042" || r.SeverityNumber != 13 || r.SeverityText != "WARN" || *r.Body.StringValue != "full" {
+		t.Fatalf("unexpected record %+v", r)
+	}
+	if r.TraceID != "4bf92f3577b34da6a3ce929d0e0e4736" || r.SpanID != "00f067aa0ba902b7" {
+		t.Fatalf("unexpected ids %q %q", r.TraceID, r.SpanID)
+	}
+	attrs := map[string]value{}
+	for _, a := range r.Attributes {
+		attrs[a.Key] = a.Value
+	}
+	if len(attrs) != 5 || *attrs["code.file.path"].StringValue != "game/room.go" || *attrs["code.line.number"].IntValue != "12" ||
+		*attrs["uid"].IntValue != "7" || *attrs["ok"].BoolValue || *attrs["rate"].DoubleValue != 0.5 {
+		t.Fatalf("unexpected attributes %+v", r.Attributes)
+	}
+	r = rl.ScopeLogs[1].LogRecords[0]
+	if r.SeverityNumber != 17 || r.TraceID != "" || len(r.Attributes) != 2 || r.Attributes[0].Key != "trace_id" || r.Attributes[1].Key != "exception.stacktrace" {
+		t.Fatalf("unexpected record %+v", r)
+	}
+	_ = o.Close()
+}
+
+func TestOTLPProtobuf(t *testing.T) {
+	rec := &httpRecorder{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Header.Get("Content-Type") != "application/x-protobuf" {
+			t.Errorf("unexpected content type %v", req.Header.Get("Content-Type"))
+		}
+		rec.record(t, req)
+	}))
+	defer srv.Close()
+
+	o := NewOTLP(srv.URL + otlpLogsPath)
+	o.Protobuf = true
+	o.Resource = map[string]string{"deployment.environment": "test"}
+	o.Write(&Message{Level: LevelInfo, Time: time.Unix(1, 2), Content: "pb",
+		Fields: []Field{Any("trace_id", [16]byte{1}), Any("span_id", []byte{0, 0, 0, 0, 0, 0, 0, 2}), Int("zero", 0), Float64("pi", math.Pi)}})
+	if err := o.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if rec.count() != 1 {
+		t.Fatalf("expected 1 request, got %d", rec.count())
+	}
+	rl := decodeProto(t, decodeProto(t, rec.bodies[0])[1][0])
+	kv := decodeProto(t, decodeProto(t, rl[1][0])[1][0])
+	if string(kv[1][0]) != "deployment.environment" || string(decodeProto(t, kv[2][0])[1][0]) != "test" {
+		t.Fatalf("unexpected resource %v", kv)
+	}
+	r := decodeProto(t, decodeProto(t, rl[2][0])[2][0])
+	if ts := binary.LittleEndian.Uint64(r[1][0]); ts != 1000000002 {
+		t.Fatalf("unexpected time %d", ts)
+	}
+	if n, _ := binary.Uvarint(r[2][0]); n != 9 || string(r[3][0]) != "INFO" {
+		t.Fatalf("unexpected severity %d %s", n, r[3][0])
+	}
+	if body := decodeProto(t, r[5][0]); string(body[1][0]) != "pb" {
+		t.Fatalf("unexpected body %v", body)
+	}
+	if len(r[9][0]) != 16 || r[9][0][0] != 1 || len(r[10][0]) != 8 || r[10][0][7] != 2 {
+		t.Fatalf("unexpected ids %x %x", r[9], r[10])
+	}
+	if len(r[6]) != 2 {
+		t.Fatalf("expected 2 attributes, got %d", len(r[6]))
+	}
+	zero := decodeProto(t, decodeProto(t, r[6][0])[2][0])
+	if v, ok := zero[3]; !ok || v[0][0] != 0 {
+		t.Fatalf("zero int value not encoded: %v", zero)
+	}
+	pi := decodeProto(t, decodeProto(t, r[6][1])[2][0])
+	if math.Float64frombits(binary.LittleEndian.Uint64(pi[4][0])) != math.Pi {
+		t.Fatalf("unexpected double %v", pi)
+	}
+}
@@ -1,7 +1,10 @@
 package logger
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"sync"
 )
 
 // Output Output输出时是否对字体染色
@@ -10,10 +13,100 @@ type Output interface {
 	Close() error
 }
 
-func (log *Logger) SetOutput(name string, output Output) error {
+// Flusher Output 可选实现的接口，将缓冲中的日志写出，Flush 返回时数据已经写出
+type Flusher interface {
+	Flush(ctx context.Context) error
+}
+
+// Flush 刷新所有实现了 Flusher 的输出，等待全部完成或者 ctx 结束
+func (log *Logger) Flush(ctx context.Context) error {
+	outputs := log.outputs
+	var wg sync.WaitGroup
+	errs := make(chan error, len(outputs))
+	for _, output := range outputs {
+		if flusher, ok := output.(Flusher); ok {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				errs <- flusher.Flush(ctx)
+			}()
+		}
+	}
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	close(errs)
+	var r []error
+	for err := range errs {
+		if err != nil {
+			r = append(r, err)
+		}
+	}
+	return errors.Join(r...)
+}
+
+// OutputOption 注册输出时的选项
+type OutputOption func(*outputFilter)
+
+// OutputLevel 输出的最低日志等级，低于该等级的日志不会写入该输出
+// 注意: 日志器等级先于输出等级判断，日志器等级需要不高于各个输出的等级
+func OutputLevel(level Level) OutputOption {
+	return func(o *outputFilter) {
+		o.level = level
+	}
+}
+
+// OutputFilter 输出的过滤器，返回 false 的日志不会写入该输出
+func OutputFilter(filter func(*Message) bool) OutputOption {
+	return func(o *outputFilter) {
+		o.filter = filter
+	}
+}
+
+// outputFilter 按等级和过滤器筛选日志的输出包装
+type outputFilter struct {
+	Output
+	level  Level
+	filter func(*Message) bool
+}
+
+func (o *outputFilter) Write(msg *Message) {
+	if msg.Level < o.level {
+		return
+	}
+	if o.filter != nil && !o.filter(msg) {
+		return
+	}
+	o.Output.Write(msg)
+}
+
+func (o *outputFilter) Flush(ctx context.Context) error {
+	if flusher, ok := o.Output.(Flusher); ok {
+		return flusher.Flush(ctx)
+	}
+	return nil
+}
+
+// SetOutput 注册输出，可以通过 OutputLevel, OutputFilter 设置该输出单独的等级和过滤器
+// 例如: SetOutput("file", file, OutputLevel(LevelDebug)) ; SetOutput(Console.Name(), Console, OutputLevel(LevelWarn))
+func (log *Logger) SetOutput(name string, output Output, opts ...OutputOption) error {
 	if _, ok := log.outputs[name]; ok {
 		return fmt.Errorf("adapter name exist:%v", name)
 	}
+	if len(opts) > 0 {
+		o := &outputFilter{Output: output}
+		for _, opt := range opts {
+			opt(o)
+		}
+		output = o
+	}
 	log.mutex.Lock()
 	defer log.mutex.Unlock()
 	dict := make(map[string]Output)
@@ -21,7 +114,7 @@ func (log *Logger) SetOutput(name string, output Output) error {
 		dict[k] = v
 	}
 	dict[name] = output
-	log.outputs = dict
+	log.setOutputs(dict)
 	return nil
 }
 
@@ -37,5 +130,22 @@ func (log *Logger) DelOutput(name string) {
 			dict[k] = v
 		}
 	}
+	log.setOutputs(dict)
+}
+
+// setOutputs 替换输出列表并更新最低输出等级，调用时需要持有锁
+func (log *Logger) setOutputs(dict map[string]Output) {
+	level := LevelFatal
+	for _, output := range dict {
+		if o, ok := output.(*outputFilter); ok {
+			level = min(level, o.level)
+		} else {
+			level = LevelDebug
+		}
+	}
+	if len(dict) == 0 {
+		level = LevelDebug
+	}
 	log.outputs = dict
+	log.outputLevel = level
 }
@@ -0,0 +1,59 @@
+package logger
+
+import "encoding/binary"
+
+// Protocol Buffers 编码，只实现 Loki 和 OTLP 需要的类型，零值字段按 proto3 规则省略
+
+const (
+	protoVarint  = 0
+	protoFixed64 = 1
+	protoBytes   = 2
+)
+
+func appendProtoTag(buf *Buffer, field int, wire int) {
+	buf.B = binary.AppendUvarint(buf.B, uint64(field)<<3|uint64(wire))
+}
+
+func appendProtoVarint(buf *Buffer, field int, v uint64) {
+	if v == 0 {
+		return
+	}
+	appendProtoTag(buf, field, protoVarint)
+	buf.B = binary.AppendUvarint(buf.B, v)
+}
+
+func appendProtoFixed64(buf *Buffer, field int, v uint64) {
+	if v == 0 {
+		return
+	}
+	appendProtoTag(buf, field, protoFixed64)
+	buf.B = binary.LittleEndian.AppendUint64(buf.B, v)
+}
+
+func appendProtoString(buf *Buffer, field int, s string) {
+	if s == "" {
+		return
+	}
+	appendProtoTag(buf, field, protoBytes)
+	buf.B = binary.AppendUvarint(buf.B, uint64(len(s)))
+	buf.WriteString(s)
+}
+
+func appendProtoBytes(buf *Buffer, field int, p []byte) {
+	if len(p) == 0 {
+		return
+	}
+	appendProtoTag(buf, field, protoBytes)
+	buf.B = binary.AppendUvarint(buf.B, uint64(len(p)))
+	buf.Write(p)
+}
+
+// appendProtoMessage 编码嵌套消息，空消息也会写入
+func appendProtoMessage(buf *Buffer, field int, f func(m *Buffer)) {
+	m := NewBuffer()
+	defer m.Free()
+	f(m)
+	appendProtoTag(buf, field, protoBytes)
+	buf.B = binary.AppendUvarint(buf.B, uint64(m.Len()))
+	buf.Write(m.B)
+}
@@ -0,0 +1,75 @@
+package logger
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+)
+
+// files 所有未关闭的 File，用于收到信号时重新打开日志文件
+var files = struct {
+	sync.Mutex
+	m map[*File]struct{}
+}{m: map[*File]struct{}{}}
+
+func registerFile(f *File) {
+	files.Lock()
+	defer files.Unlock()
+	files.m[f] = struct{}{}
+}
+
+func unregisterFile(f *File) {
+	files.Lock()
+	defer files.Unlock()
+	delete(files.m, f)
+}
+
+// ReopenFiles 重新打开所有未关闭的 File 的日志文件
+func ReopenFiles() error {
+	files.Lock()
+	list := make([]*File, 0, len(files.m))
+	for f := range files.m {
+		list = append(list, f)
+	}
+	files.Unlock()
+	var errs []error
+	for _, f := range list {
+		if err := f.Reopen(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// ReopenOnSignal 收到信号时调用 ReopenFiles，默认 SIGHUP，返回的函数用于停止监听
+// 用于配合 logrotate 的 postrotate 脚本，例如: kill -HUP <pid>
+func ReopenOnSignal(sig ...os.Signal) (stop func()) {
+	if len(sig) == 0 {
+		sig = []os.Signal{syscall.SIGHUP}
+	}
+	c := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(c, sig...)
+	go func() {
+		for {
+			select {
+			case <-c:
+				if err := ReopenFiles(); err != nil {
+					fmt.Printf("logger reopen files error:%v", err)
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			signal.Stop(c)
+			close(done)
+		})
+	}
+}
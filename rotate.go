@@ -0,0 +1,191 @@
+package logger
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+// rotatePeriod 切分周期
+type rotatePeriod int8
+
+const (
+	rotateNever rotatePeriod = iota
+	rotateMinute
+	rotateHour
+	rotateDay
+	rotateWeek
+	rotateMonth
+	rotateYear
+)
+
+// rotateStart t 所在周期的开始时间，按本地日历计算
+// 分钟和小时从 t 中减去本地的分和秒，夏令时切换的那个小时不会重复或者跳过
+func rotateStart(t time.Time, p rotatePeriod) time.Time {
+	sub := time.Duration(t.Second())*time.Second + time.Duration(t.Nanosecond())
+	switch p {
+	case rotateMinute:
+		return t.Add(-sub)
+	case rotateHour:
+		return t.Add(-sub - time.Duration(t.Minute())*time.Minute)
+	case rotateDay:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	case rotateWeek:
+		// 每周从周一开始
+		offset := (int(t.Weekday()) + 6) % 7
+		return time.Date(t.Year(), t.Month(), t.Day()-offset, 0, 0, 0, 0, t.Location())
+	case rotateMonth:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+	case rotateYear:
+		return time.Date(t.Year(), 1, 1, 0, 0, 0, 0, t.Location())
+	}
+	return t
+}
+
+// rotateNext 下一个周期的开始时间，按天以上的周期使用 time.Date 计算，夏令时切换的日期不是24小时
+func rotateNext(start time.Time, p rotatePeriod) time.Time {
+	switch p {
+	case rotateMinute:
+		return start.Add(time.Minute)
+	case rotateHour:
+		return start.Add(time.Hour)
+	case rotateDay:
+		return time.Date(start.Year(), start.Month(), start.Day()+1, 0, 0, 0, 0, start.Location())
+	case rotateWeek:
+		return time.Date(start.Year(), start.Month(), start.Day()+7, 0, 0, 0, 0, start.Location())
+	case rotateMonth:
+		return time.Date(start.Year(), start.Month()+1, 1, 0, 0, 0, 0, start.Location())
+	case rotateYear:
+		return time.Date(start.Year()+1, 1, 1, 0, 0, 0, 0, start.Location())
+	}
+	return time.Time{}
+}
+
+// fileSchedule 按周期切分的文件名规则，当前文件名固定，备份名后缀为周期的开始时间
+type fileSchedule struct {
+	name   string
+	layout string        //备份名后缀格式
+	period rotatePeriod  //切分周期
+	every  time.Duration //自定义间隔，大于0时代替 period
+}
+
+func (s *fileSchedule) format(t time.Time) (name, backup string, expire int64) {
+	if s.every > 0 {
+		day := rotateStart(t, rotateDay)
+		next := rotateNext(day, rotateDay)
+		start := day.Add(t.Sub(day) / s.every * s.every)
+		// 间隔从每天零点开始对齐，不会跨越零点
+		end := start.Add(s.every)
+		if end.After(next) {
+			end = next
+		}
+		return s.name, start.Format(s.layout), end.Unix()
+	}
+	start := rotateStart(t, s.period)
+	return s.name, start.Format(s.layout), rotateNext(start, s.period).Unix()
+}
+
+func (s *fileSchedule) formatter() fileNameFormatter {
+	return func() (string, string, int64) {
+		return s.format(time.Now())
+	}
+}
+
+// RotateHourly 每小时切分，备份名例如 name.2006010215.0002.ext
+func RotateHourly(name string) fileNameFormatter {
+	return (&fileSchedule{name: name, layout: "2006010215", period: rotateHour}).formatter()
+}
+
+// RotateDaily 每天零点切分，备份名例如 name.20060102.0002.ext
+func RotateDaily(name string) fileNameFormatter {
+	return (&fileSchedule{name: name, layout: "20060102", period: rotateDay}).formatter()
+}
+
+// RotateWeekly 每周一零点切分，备份名后缀为周一的日期
+func RotateWeekly(name string) fileNameFormatter {
+	return (&fileSchedule{name: name, layout: "20060102", period: rotateWeek}).formatter()
+}
+
+// RotateMonthly 每月1日零点切分，备份名例如 name.200601.0002.ext
+func RotateMonthly(name string) fileNameFormatter {
+	return (&fileSchedule{name: name, layout: "200601", period: rotateMonth}).formatter()
+}
+
+// RotateEvery 按固定间隔切分，间隔从每天零点开始对齐，例如 15*time.Minute 在 00:00, 00:15 ... 切分
+// 间隔最小为1分钟，备份名例如 name.200601021504.0002.ext
+func RotateEvery(name string, d time.Duration) fileNameFormatter {
+	if d < time.Minute {
+		d = time.Minute
+	}
+	return (&fileSchedule{name: name, layout: "200601021504", every: d}).formatter()
+}
+
+// filePatternVerbs 文件名模式支持的时间格式
+var filePatternVerbs = map[byte]struct {
+	layout string
+	regexp string
+	period rotatePeriod
+}{
+	'Y': {"2006", `\d{4}`, rotateYear},
+	'y': {"06", `\d{2}`, rotateYear},
+	'm': {"01", `\d{2}`, rotateMonth},
+	'd': {"02", `\d{2}`, rotateDay},
+	'H': {"15", `\d{2}`, rotateHour},
+	'M': {"04", `\d{2}`, rotateMinute},
+}
+
+// parseFilePattern 将 %Y 等替换成 time 格式，返回最小的时间单位和匹配文件名的正则表达式
+func parseFilePattern(pattern string) (layout []string, period rotatePeriod, re string) {
+	var literal strings.Builder
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		if c == '%' && i+1 < len(pattern) {
+			if v, ok := filePatternVerbs[pattern[i+1]]; ok {
+				layout = append(layout, literal.String(), v.layout)
+				re += regexp.QuoteMeta(literal.String()) + v.regexp
+				literal.Reset()
+				if period == rotateNever || v.period < period {
+					period = v.period
+				}
+				i++
+				continue
+			}
+			if pattern[i+1] == '%' {
+				i++
+			}
+		}
+		literal.WriteByte(c)
+	}
+	layout = append(layout, literal.String())
+	re += regexp.QuoteMeta(literal.String())
+	return
+}
+
+// fileNamePattern 带时间的文件名模式，SetFileName 根据模式清理和压缩以前生成的文件
+type fileNamePattern string
+
+// FileNamePattern 使用带时间的文件名，支持 %Y %y %m %d %H %M 和 %%，例如 app-%Y%m%d.log 每天生成一个文件
+// 按模式中最小的时间单位切分，文件名已经包含时间，不会再备份，按大小切分无效
+// 用于 File.SetFileName，与直接传入包含 % 的文件名相同
+func FileNamePattern(pattern string) fileNamePattern {
+	return fileNamePattern(pattern)
+}
+
+func (p fileNamePattern) formatter() fileNameFormatter {
+	layout, period, _ := parseFilePattern(string(p))
+	return func() (name, backup string, expire int64) {
+		t := time.Now()
+		var b strings.Builder
+		for i, s := range layout {
+			if i%2 == 1 {
+				b.WriteString(t.Format(s))
+			} else {
+				b.WriteString(s)
+			}
+		}
+		if period != rotateNever {
+			expire = rotateNext(rotateStart(t, period), period).Unix()
+		}
+		return b.String(), "", expire
+	}
+}
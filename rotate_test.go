@@ -0,0 +1,127 @@
+package logger
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestRotateSchedule(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("load location: %v", err)
+	}
+	at := func(s string) time.Time {
+		v, err := time.ParseInLocation("2006-01-02 15:04", s, loc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+	// 2026-11-01 02:00 EDT 回拨到 01:00 EST，2026-03-08 02:00 EST 拨快到 03:00 EDT
+	fallBack := at("2026-11-01 00:30").Add(time.Hour)
+	cases := []struct {
+		schedule *fileSchedule
+		now      time.Time
+		backup   string
+		expire   time.Time
+	}{
+		{&fileSchedule{layout: "2006010215", period: rotateHour}, fallBack, "2026110101", fallBack.Add(30 * time.Minute)},
+		{&fileSchedule{layout: "2006010215", period: rotateHour}, fallBack.Add(time.Hour), "2026110101", fallBack.Add(90 * time.Minute)},
+		{&fileSchedule{layout: "20060102", period: rotateDay}, at("2026-03-08 00:30"), "20260308", at("2026-03-08 00:30").Add(22*time.Hour + 30*time.Minute)},
+		{&fileSchedule{layout: "20060102", period: rotateDay}, at("2026-11-01 00:30"), "20261101", at("2026-11-01 00:30").Add(24*time.Hour + 30*time.Minute)},
+		{&fileSchedule{layout: "20060102", period: rotateWeek}, at("2026-10-17 08:00"), "20261012", at("2026-10-19 00:00")},
+		{&fileSchedule{layout: "20060102", period: rotateWeek}, at("2026-10-19 00:00"), "20261019", at("2026-10-26 00:00")},
+		{&fileSchedule{layout: "200601", period: rotateMonth}, at("2026-12-15 08:00"), "202612", at("2027-01-01 00:00")},
+		{&fileSchedule{layout: "200601021504", every: 15 * time.Minute}, at("2026-10-17 10:07"), "202610171000", at("2026-10-17 10:15")},
+		{&fileSchedule{layout: "200601021504", every: 7 * time.Hour}, at("2026-10-17 22:00"), "202610172100", at("2026-10-18 00:00")},
+		{&fileSchedule{layout: "200601021504", every: 15 * time.Minute}, at("2026-03-08 03:10"), "202603080300", at("2026-03-08 03:15")},
+	}
+	for i, c := range cases {
+		_, backup, expire := c.schedule.format(c.now)
+		if backup != c.backup || expire != c.expire.Unix() {
+			t.Errorf("case %d: got %s %v, want %s %v", i, backup, time.Unix(expire, 0).In(loc), c.backup, c.expire)
+		}
+	}
+}
+
+func TestFileNamePattern(t *testing.T) {
+	name, backup, expire := FileNamePattern("app-%Y%m%d-%%-%q.log").formatter()()
+	now := time.Now()
+	if want := now.Format("app-20060102-%-%q.log"); name != want || backup != "" {
+		t.Fatalf("got %q %q, want %q", name, backup, want)
+	}
+	if next := rotateNext(rotateStart(now, rotateDay), rotateDay); expire != next.Unix() {
+		t.Fatalf("expire %v, want %v", time.Unix(expire, 0), next)
+	}
+	_, _, expr := parseFilePattern("app-%Y%m%d.log")
+	re := regexp.MustCompile(`^` + expr + `$`)
+	for s, want := range map[string]bool{"app-20261017.log": true, "app-2026.log": false, "app-20261017xlog": false} {
+		if re.MatchString(s) != want {
+			t.Errorf("match %q, want %v", s, want)
+		}
+	}
+	if _, _, expire = FileNamePattern("app.log").formatter()(); expire != 0 {
+		t.Fatalf("pattern without time should not expire")
+	}
+}
+
+// TestFilePatternIgnoresSize 测试文件名模式下不会按大小反复重新创建文件
+func TestFilePatternIgnoresSize(t *testing.T) {
+	fd, err := os.CreateTemp(t.TempDir(), "app-*.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fd.Close()
+	f := &File{limit: 1, fs: &fileSystem{file: fd, size: 100}}
+	if f.mayNeedBackup() {
+		t.Fatalf("size limit applied without backup name")
+	}
+	f.fs.backup = "202610"
+	if !f.mayNeedBackup() {
+		t.Fatalf("size limit ignored with backup name")
+	}
+}
+
+// TestFilePatternRetention 测试文件名模式生成的旧文件参与清理和压缩，字符串和 FileNamePattern 两种设置方式相同
+func TestFilePatternRetention(t *testing.T) {
+	for _, pattern := range []any{"app-%Y%m%d.log", FileNamePattern("app-%Y%m%d.log")} {
+		dir := t.TempDir()
+		now := time.Now()
+		for name, age := range map[string]time.Duration{
+			"app-20200101.log":    48 * time.Hour,
+			"app-20200102.log.gz": 24 * time.Hour,
+			"app-20200103.log":    time.Hour,
+			"app.log":             72 * time.Hour,
+		} {
+			path := filepath.Join(dir, name)
+			if err := os.WriteFile(path, []byte("old\n"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.Chtimes(path, now.Add(-age), now.Add(-age)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		f := NewFile(dir)
+		f.SetFileName(pattern)
+		f.SetMaxBackups(2)
+		f.SetCompress(true)
+		f.Write(&Message{Level: LevelInfo, Time: now, Content: "pattern"})
+		_ = f.Close()
+
+		for name, want := range map[string]bool{
+			now.Format("app-20060102.log"): true,
+			"app-20200101.log":             false,
+			"app-20200102.log.gz":          true,
+			"app-20200103.log":             false,
+			"app-20200103.log.gz":          true,
+			"app.log":                      true,
+		} {
+			if exists := f.fileExists(filepath.Join(dir, name)); exists != want {
+				t.Errorf("%T: %s exists=%v, want %v", pattern, name, exists, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"runtime"
+)
+
+// 日志等级与 slog.Level 的对应关系
+var slogLevels = map[Level]slog.Level{
+	LevelDebug: slog.LevelDebug,
+	LevelTrace: slog.LevelDebug + 2,
+	LevelInfo:  slog.LevelInfo,
+	LevelWarn:  slog.LevelWarn,
+	LevelAlert: slog.LevelWarn + 2,
+	LevelError: slog.LevelError,
+	LevelPanic: slog.LevelError + 4,
+	LevelFatal: slog.LevelError + 8,
+}
+
+// SlogLevel 将日志等级转换成 slog.Level
+func SlogLevel(l Level) slog.Level {
+	if v, ok := slogLevels[l]; ok {
+		return v
+	}
+	if l < LevelDebug {
+		return slog.LevelDebug
+	}
+	return slogLevels[LevelFatal]
+}
+
+// LevelFromSlog 将 slog.Level 转换成日志等级，取不大于该值的最高等级
+func LevelFromSlog(l slog.Level) Level {
+	r := LevelDebug
+	for level := LevelDebug; level <= LevelFatal; level++ {
+		if slogLevels[level] <= l {
+			r = level
+		}
+	}
+	return r
+}
+
+// SlogHandler 以 *Logger 为后端的 slog.Handler
+type SlogHandler struct {
+	logger *Logger
+	group  string // 当前分组前缀，例如: http.request.
+}
+
+// NewSlogHandler 创建 slog.Handler, 用法: slog.New(logger.NewSlogHandler(l))
+func NewSlogHandler(l *Logger) *SlogHandler {
+	return &SlogHandler{logger: l}
+}
+
+func (h *SlogHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return h.logger.Enabled(LevelFromSlog(level))
+}
+
+func (h *SlogHandler) Handle(_ context.Context, r slog.Record) error {
+	msg := &Message{Time: r.Time, Level: LevelFromSlog(r.Level), Content: r.Message}
+	if r.PC != 0 {
+		frame, _ := runtime.CallersFrames([]uintptr{r.PC}).Next()
+		if frame.File != "" {
+			msg.Path = h.logger.trimPath(frame.File, frame.Line)
+		}
+	}
+	if r.NumAttrs() > 0 {
+		msg.Fields = make([]Field, 0, r.NumAttrs())
+		r.Attrs(func(a slog.Attr) bool {
+			msg.Fields = appendSlogAttr(msg.Fields, h.group, a)
+			return true
+		})
+	}
+	h.logger.Write(msg)
+	return nil
+}
+
+func (h *SlogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	var fields []Field
+	for _, a := range attrs {
+		fields = appendSlogAttr(fields, h.group, a)
+	}
+	if len(fields) == 0 {
+		return h
+	}
+	return &SlogHandler{logger: h.logger.With(fields), group: h.group}
+}
+
+func (h *SlogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return &SlogHandler{logger: h.logger, group: h.group + name + "."}
+}
+
+// appendSlogAttr 将 slog.Attr 展开成字段，分组以 . 连接到键名中
+func appendSlogAttr(fields []Field, prefix string, a slog.Attr) []Field {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return fields
+	}
+	if a.Value.Kind() == slog.KindGroup {
+		attrs := a.Value.Group()
+		if len(attrs) == 0 {
+			return fields
+		}
+		if a.Key != "" {
+			prefix = prefix + a.Key + "."
+		}
+		for _, v := range attrs {
+			fields = appendSlogAttr(fields, prefix, v)
+		}
+		return fields
+	}
+	return append(fields, Field{Key: prefix + a.Key, Value: a.Value.Any()})
+}
+
+// SlogOutput 将日志转发到任意 slog.Handler 的输出
+type SlogOutput struct {
+	handler slog.Handler
+}
+
+func NewSlogOutput(h slog.Handler) *SlogOutput {
+	return &SlogOutput{handler: h}
+}
+
+func (s *SlogOutput) Write(msg *Message) {
+	ctx := context.Background()
+	level := SlogLevel(msg.Level)
+	if !s.handler.Enabled(ctx, level) {
+		return
+	}
+	r := slog.NewRecord(msg.Time, level, msg.Content, 0)
+	if msg.Name != "" {
+		r.AddAttrs(slog.String("logger", msg.Name))
+	}
+	if msg.Path != "" {
+		r.AddAttrs(slog.String("path", msg.Path))
+	}
+	for _, f := range msg.Fields {
+		r.AddAttrs(slog.Any(f.Key, f.Value))
+	}
+	if msg.Stack != "" {
+		r.AddAttrs(slog.String("stack", msg.Stack))
+	}
+	_ = s.handler.Handle(ctx, r)
+}
+
+func (s *SlogOutput) Close() error {
+	return nil
+}
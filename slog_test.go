@@ -0,0 +1,82 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestSlogLevel(t *testing.T) {
+	for level := LevelDebug; level <= LevelFatal; level++ {
+		if r := LevelFromSlog(SlogLevel(level)); r != level {
+			t.Errorf("LevelFromSlog(SlogLevel(%v)) = %v", level, r)
+		}
+	}
+	if r := LevelFromSlog(slog.LevelDebug - 4); r != LevelDebug {
+		t.Errorf("LevelFromSlog(debug-4) = %v", r)
+	}
+	if r := LevelFromSlog(slog.LevelError + 1); r != LevelError {
+		t.Errorf("LevelFromSlog(error+1) = %v", r)
+	}
+}
+
+func TestSlogHandler(t *testing.T) {
+	log, out := newMemoryLogger()
+	log.SetLevel(LevelInfo)
+	l := slog.New(NewSlogHandler(log)).With("uid", 7).WithGroup("req").With("id", "r-1")
+
+	l.Debug("ignored")
+	l.Warn("slow", slog.Group("db", slog.Int("rows", 3)), slog.Group("", slog.Bool("inline", true)))
+
+	msg := out.last(t)
+	if msg.Level != LevelWarn || msg.Content != "slow" {
+		t.Fatalf("unexpected message: %+v", msg)
+	}
+	if !strings.Contains(msg.Path, "slog_test.go:") {
+		t.Errorf("Path = %q", msg.Path)
+	}
+	keys := make([]string, 0, len(msg.Fields))
+	for _, f := range msg.Fields {
+		keys = append(keys, f.Key)
+	}
+	if got := strings.Join(keys, ","); got != "uid,req.id,req.db.rows,req.inline" {
+		t.Errorf("keys = %s", got)
+	}
+	if len(out.messages) != 1 {
+		t.Errorf("debug message not filtered: %d", len(out.messages))
+	}
+}
+
+func TestSlogHandlerEnabled(t *testing.T) {
+	log := New()
+	log.SetLevel(LevelDebug)
+	_ = log.SetOutput("warn", &memoryOutput{}, OutputLevel(LevelWarn))
+	h := NewSlogHandler(log)
+	if h.Enabled(context.Background(), slog.LevelInfo) {
+		t.Errorf("info enabled below output level")
+	}
+	if !h.Enabled(context.Background(), slog.LevelWarn) {
+		t.Errorf("warn disabled")
+	}
+}
+
+func TestSlogOutput(t *testing.T) {
+	buf := &bytes.Buffer{}
+	log := New()
+	log.SetLevel(LevelDebug)
+	_ = log.SetOutput("slog", NewSlogOutput(slog.NewJSONHandler(buf, &slog.HandlerOptions{Level: slog.LevelInfo})))
+
+	log.Debug("ignored")
+	log.Named("game").Alertw("boss", "hp", 100)
+
+	var r map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &r); err != nil {
+		t.Fatalf("invalid json %q: %v", buf.String(), err)
+	}
+	if r["msg"] != "boss" || r["logger"] != "game" || r["hp"] != float64(100) || r["level"] != "WARN+2" {
+		t.Errorf("unexpected record: %v", r)
+	}
+}
@@ -0,0 +1,84 @@
+package logger
+
+import "encoding/binary"
+
+// snappy 块格式压缩(不含帧格式)，Loki 的 protobuf 请求体使用
+// 输入按64K分块，块内使用哈希表查找4字节重复，偏移不会超过 copy2 的范围
+
+const (
+	snappyBlockSize = 1 << 16
+	snappyTableBits = 14
+	snappyMinInput  = 17 //过短的块直接作为字面量
+)
+
+func snappyEncode(dst, src []byte) []byte {
+	dst = binary.AppendUvarint(dst, uint64(len(src)))
+	var table []int32
+	for len(src) > 0 {
+		p := src
+		if len(p) > snappyBlockSize {
+			p = p[:snappyBlockSize]
+		}
+		src = src[len(p):]
+		if len(p) < snappyMinInput {
+			dst = snappyLiteral(dst, p)
+			continue
+		}
+		if table == nil {
+			table = make([]int32, 1<<snappyTableBits)
+		} else {
+			clear(table)
+		}
+		dst = snappyEncodeBlock(dst, p, table)
+	}
+	return dst
+}
+
+// snappyEncodeBlock 压缩一个块，table 中保存位置+1，0表示空
+func snappyEncodeBlock(dst, p []byte, table []int32) []byte {
+	emit := 0
+	for s := 0; s+4 <= len(p); {
+		v := binary.LittleEndian.Uint32(p[s:])
+		h := (v * 0x1e35a7bd) >> (32 - snappyTableBits)
+		c := int(table[h]) - 1
+		table[h] = int32(s + 1)
+		if c < 0 || binary.LittleEndian.Uint32(p[c:]) != v {
+			s++
+			continue
+		}
+		dst = snappyLiteral(dst, p[emit:s])
+		n := 4
+		for s+n < len(p) && p[c+n] == p[s+n] {
+			n++
+		}
+		dst = snappyCopy(dst, s-c, n)
+		s += n
+		emit = s
+	}
+	return snappyLiteral(dst, p[emit:])
+}
+
+func snappyLiteral(dst, p []byte) []byte {
+	n := len(p) - 1
+	switch {
+	case n < 0:
+		return dst
+	case n < 60:
+		dst = append(dst, byte(n)<<2)
+	case n < 1<<8:
+		dst = append(dst, 60<<2, byte(n))
+	default:
+		dst = append(dst, 61<<2, byte(n), byte(n>>8))
+	}
+	return append(dst, p...)
+}
+
+// snappyCopy 使用 copy2 格式，每段最长64字节
+func snappyCopy(dst []byte, offset, length int) []byte {
+	for length > 0 {
+		n := min(length, 64)
+		dst = append(dst, byte(n-1)<<2|2, byte(offset), byte(offset>>8))
+		length -= n
+	}
+	return dst
+}
@@ -0,0 +1,244 @@
+package logger
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	spoolExt                = ".spool"
+	defaultSpoolLimit       = 100 * 1024 * 1024 // 默认最多占用100M磁盘空间
+	defaultSpoolSegmentSize = 4 * 1024 * 1024   // 单个分段文件大小
+	spoolHeaderSize         = 4                 // 记录头，保存记录长度(大端)
+)
+
+// spool 磁盘缓存，网络不可用时将日志按顺序追加到分段文件中，恢复后按顺序重发
+// 文件名为递增的序号，记录格式为 4字节长度 + 日志内容，超过容量上限时删除最旧的分段
+// 只在 Conn.process 协程中使用，无需加锁
+type spool struct {
+	dir         string
+	limit       int64           //总容量上限
+	segmentSize int64           //分段文件大小
+	size        int64           //当前总大小
+	segments    []*spoolSegment //由旧到新
+	writer      *os.File        //最新分段的写句柄
+	reader      *os.File        //最旧分段的读句柄
+}
+
+type spoolSegment struct {
+	seq     int64
+	size    int64
+	offset  int64 //已经发送的位置，只对最旧的分段有效
+	records int64 //未发送的记录数
+}
+
+// openSpool 打开缓存目录，目录中已有的分段会在连接恢复后继续发送
+func openSpool(dir string, limit int64) (*spool, error) {
+	if limit <= 0 {
+		limit = defaultSpoolLimit
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	s := &spool{dir: dir, limit: limit, segmentSize: min(defaultSpoolSegmentSize, max(limit/4, 1))}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, spoolExt) {
+			continue
+		}
+		seq, err := strconv.ParseInt(strings.TrimSuffix(name, spoolExt), 10, 64)
+		if err != nil {
+			continue
+		}
+		seg := &spoolSegment{seq: seq}
+		if seg.size, seg.records, err = s.scan(seg); err != nil {
+			return nil, err
+		}
+		if seg.records == 0 {
+			_ = os.Remove(s.path(seg))
+			continue
+		}
+		s.segments = append(s.segments, seg)
+		s.size += seg.size
+	}
+	sort.Slice(s.segments, func(i, j int) bool { return s.segments[i].seq < s.segments[j].seq })
+	return s, nil
+}
+
+// scan 统计分段中完整记录的数量，崩溃时写了一半的记录会被忽略
+func (s *spool) scan(seg *spoolSegment) (size, records int64, err error) {
+	f, err := os.Open(s.path(seg))
+	if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return 0, 0, err
+	}
+	var header [spoolHeaderSize]byte
+	for {
+		if _, err = f.ReadAt(header[:], size); err != nil {
+			break
+		}
+		n := int64(binary.BigEndian.Uint32(header[:]))
+		if size+spoolHeaderSize+n > fi.Size() {
+			break
+		}
+		size += spoolHeaderSize + n
+		records++
+	}
+	return size, records, nil
+}
+
+func (s *spool) path(seg *spoolSegment) string {
+	return filepath.Join(s.dir, fmt.Sprintf("%020d%s", seg.seq, spoolExt))
+}
+
+func (s *spool) empty() bool {
+	return len(s.segments) == 0
+}
+
+// append 追加一条记录，返回因超过容量而删除的记录数
+func (s *spool) append(p []byte) (evicted int64, err error) {
+	tail := s.tail()
+	if tail == nil || s.writer == nil || tail.size >= s.segmentSize {
+		if tail, err = s.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	var header [spoolHeaderSize]byte
+	binary.BigEndian.PutUint32(header[:], uint32(len(p)))
+	if _, err = s.writer.Write(append(header[:], p...)); err != nil {
+		s.discard(tail)
+		return 0, err
+	}
+	n := int64(spoolHeaderSize + len(p))
+	tail.size += n
+	tail.records++
+	s.size += n
+	for s.size > s.limit && len(s.segments) > 1 {
+		evicted += s.segments[0].records
+		s.remove()
+	}
+	return evicted, nil
+}
+
+// discard 写入失败后截掉写了一半的记录，截断失败时关闭该分段，下次写入新的分段
+func (s *spool) discard(tail *spoolSegment) {
+	if err := s.writer.Truncate(tail.size); err == nil {
+		if _, err = s.writer.Seek(tail.size, io.SeekStart); err == nil {
+			return
+		}
+	}
+	_ = s.writer.Close()
+	s.writer = nil
+}
+
+func (s *spool) tail() *spoolSegment {
+	if len(s.segments) == 0 {
+		return nil
+	}
+	return s.segments[len(s.segments)-1]
+}
+
+// rotate 创建新的分段文件
+func (s *spool) rotate() (*spoolSegment, error) {
+	if s.writer != nil {
+		_ = s.writer.Close()
+		s.writer = nil
+	}
+	seg := &spoolSegment{}
+	if tail := s.tail(); tail != nil {
+		seg.seq = tail.seq + 1
+	}
+	f, err := os.OpenFile(s.path(seg), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return nil, err
+	}
+	s.writer = f
+	s.segments = append(s.segments, seg)
+	return seg, nil
+}
+
+// peek 读取最旧的一条记录，读取失败时丢弃该分段剩余的内容
+func (s *spool) peek() ([]byte, error) {
+	for len(s.segments) > 0 {
+		head := s.segments[0]
+		if head.records == 0 {
+			s.remove()
+			continue
+		}
+		if s.reader == nil {
+			f, err := os.Open(s.path(head))
+			if err != nil {
+				s.remove()
+				continue
+			}
+			s.reader = f
+		}
+		var header [spoolHeaderSize]byte
+		if _, err := s.reader.ReadAt(header[:], head.offset); err != nil {
+			s.remove()
+			continue
+		}
+		p := make([]byte, binary.BigEndian.Uint32(header[:]))
+		if _, err := s.reader.ReadAt(p, head.offset+spoolHeaderSize); err != nil {
+			s.remove()
+			continue
+		}
+		return p, nil
+	}
+	return nil, io.EOF
+}
+
+// pop 确认最旧的一条记录已经发送
+func (s *spool) pop(p []byte) {
+	if len(s.segments) == 0 {
+		return
+	}
+	head := s.segments[0]
+	head.offset += int64(spoolHeaderSize + len(p))
+	head.records--
+	if head.records == 0 {
+		s.remove()
+	}
+}
+
+// remove 删除最旧的分段
+func (s *spool) remove() {
+	head := s.segments[0]
+	if s.reader != nil {
+		_ = s.reader.Close()
+		s.reader = nil
+	}
+	if len(s.segments) == 1 && s.writer != nil {
+		_ = s.writer.Close()
+		s.writer = nil
+	}
+	_ = os.Remove(s.path(head))
+	s.size -= head.size
+	s.segments = s.segments[1:]
+}
+
+// close 关闭文件句柄，未发送的记录保留在磁盘上
+func (s *spool) close() {
+	if s.reader != nil {
+		_ = s.reader.Close()
+		s.reader = nil
+	}
+	if s.writer != nil {
+		_ = s.writer.Close()
+		s.writer = nil
+	}
+}
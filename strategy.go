@@ -0,0 +1,141 @@
+package logger
+
+import (
+	"errors"
+	"time"
+)
+
+const defaultFallback = 30 * time.Second
+
+// Strategy Conn 配置多个地址时的发送策略
+type Strategy int8
+
+const (
+	StrategyFailover   Strategy = 0 // 按顺序使用第一个可用地址，备用地址期间定时检测并回切主地址
+	StrategyRoundRobin Strategy = 1 // 在所有可用地址之间轮流发送
+	StrategyBroadcast  Strategy = 2 // 发送到所有可用地址，至少一个地址成功即视为发送成功
+)
+
+// EndpointHealth 单个地址的健康状态
+type EndpointHealth struct {
+	Addr     string
+	State    ConnState
+	Sent     int64 // 发送成功的日志数量
+	Failures int64 // 连接或者发送失败的次数
+}
+
+// Endpoints 所有地址的健康状态
+func (c *Conn) Endpoints() []EndpointHealth {
+	c.start()
+	r := make([]EndpointHealth, 0, len(c.endpoints))
+	for _, ep := range c.endpoints {
+		r = append(r, EndpointHealth{
+			Addr:     ep.addr,
+			State:    ConnState(ep.health.Load()),
+			Sent:     ep.sent.Load(),
+			Failures: ep.failures.Load(),
+		})
+	}
+	return r
+}
+
+// send 按策略发送一条日志，至少一个地址发送成功时返回 true
+func (c *Conn) send(p []byte) bool {
+	if c.Strategy == StrategyBroadcast {
+		ok := false
+		for _, ep := range c.endpoints {
+			if !c.ready(ep) {
+				continue
+			}
+			if err := c.write(ep, p); c.discard(err) {
+				return true
+			} else if err != nil {
+				c.fail(ep, err)
+				continue
+			}
+			ok = true
+		}
+		return ok
+	}
+	// 发送失败时换用下一个可用地址，每个地址最多尝试一次
+	for i := 0; i <= len(c.endpoints); i++ {
+		ep := c.available()
+		if ep == nil {
+			return false
+		}
+		if err := c.write(ep, p); err == nil || c.discard(err) {
+			return true
+		} else {
+			c.fail(ep, err)
+		}
+	}
+	return false
+}
+
+// discard transmit 无法发送的日志与地址无关，直接丢弃并且只计数一次，不会断开连接
+func (c *Conn) discard(err error) bool {
+	if errors.Is(err, errConnDropped) {
+		c.dropped.Add(1)
+		return true
+	}
+	return false
+}
+
+// available 按策略返回一个可用的连接
+func (c *Conn) available() *endpoint {
+	n := len(c.endpoints)
+	if n == 0 {
+		return nil
+	}
+	if c.Strategy == StrategyRoundRobin {
+		for i := 0; i < n; i++ {
+			k := (c.current + i) % n
+			if c.ready(c.endpoints[k]) {
+				c.current = (k + 1) % n
+				return c.endpoints[k]
+			}
+		}
+		return nil
+	}
+	// StrategyFailover 使用备用地址时，按 Fallback 间隔检测排在前面的地址
+	fallback := c.Fallback
+	if fallback <= 0 {
+		fallback = defaultFallback
+	}
+	now := time.Now()
+	for i, ep := range c.endpoints {
+		if ep.conn == nil && i < c.current && c.endpoints[c.current].conn != nil && now.Sub(ep.dialAt) < fallback {
+			continue
+		}
+		if !c.ready(ep) {
+			continue
+		}
+		if i != c.current {
+			// 回切或者切换到备用地址时关闭其他连接
+			for j, other := range c.endpoints {
+				if j != i && other.conn != nil {
+					_ = other.conn.Close()
+					other.conn = nil
+				}
+			}
+			c.current = i
+		}
+		return ep
+	}
+	return nil
+}
+
+// ready 地址已经连接或者退避时间已到并且重新连接成功
+func (c *Conn) ready(ep *endpoint) bool {
+	if ep.conn != nil {
+		return true
+	}
+	if time.Now().Before(ep.retryAt) {
+		return false
+	}
+	if err := c.dial(ep); err != nil {
+		c.fail(ep, err)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,88 @@
+package logger
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func countLines(lines chan string) int {
+	n := 0
+	for {
+		select {
+		case <-lines:
+			n++
+		case <-time.After(200 * time.Millisecond):
+			return n
+		}
+	}
+}
+
+func TestStrategyRoundRobin(t *testing.T) {
+	ln1, lines1 := listenLines(t)
+	ln2, lines2 := listenLines(t)
+	c := &Conn{Network: "tcp", Address: ln1.Addr().String() + ";" + ln2.Addr().String(), Strategy: StrategyRoundRobin}
+	defer c.Close()
+	for i := 0; i < 10; i++ {
+		c.Write(&Message{Content: "rr"})
+	}
+	_ = c.Flush(context.Background())
+	if n1, n2 := countLines(lines1), countLines(lines2); n1 != 5 || n2 != 5 {
+		t.Errorf("round robin = %d/%d", n1, n2)
+	}
+	for _, h := range c.Endpoints() {
+		if h.State != ConnStateConnected || h.Sent != 5 {
+			t.Errorf("unexpected health %+v", h)
+		}
+	}
+}
+
+func TestStrategyBroadcast(t *testing.T) {
+	ln1, lines1 := listenLines(t)
+	ln2, lines2 := listenLines(t)
+	addrs := []string{ln1.Addr().String(), unusedAddr(t), ln2.Addr().String()}
+	c := &Conn{Network: "tcp", Address: strings.Join(addrs, ";"), Strategy: StrategyBroadcast, OnState: func(string, ConnState, error) {}}
+	defer c.Close()
+	for i := 0; i < 5; i++ {
+		c.Write(&Message{Content: "all"})
+	}
+	_ = c.Flush(context.Background())
+	if n1, n2 := countLines(lines1), countLines(lines2); n1 != 5 || n2 != 5 {
+		t.Errorf("broadcast = %d/%d", n1, n2)
+	}
+	if h := c.Endpoints()[1]; h.State != ConnStateDisconnected || h.Failures == 0 {
+		t.Errorf("unexpected health %+v", h)
+	}
+	if c.Dropped() != 0 {
+		t.Errorf("Dropped = %d", c.Dropped())
+	}
+}
+
+func TestStrategyFailover(t *testing.T) {
+	primary := unusedAddr(t)
+	ln, backup := listenLines(t)
+	c := &Conn{
+		Network:  "tcp",
+		Address:  primary + ";" + ln.Addr().String(),
+		Fallback: 50 * time.Millisecond,
+		Backoff:  Backoff{Initial: 10 * time.Millisecond, Max: 10 * time.Millisecond},
+		OnState:  func(string, ConnState, error) {},
+	}
+	defer c.Close()
+	c.Write(&Message{Content: "backup"})
+	if line := receiveLine(t, backup); line != "backup" {
+		t.Fatalf("unexpected line %q", line)
+	}
+
+	// 主地址恢复后回切
+	lines := acceptLines(t, primary)
+	time.Sleep(100 * time.Millisecond)
+	c.Write(&Message{Content: "primary"})
+	if line := receiveLine(t, lines); line != "primary" {
+		t.Fatalf("unexpected line %q", line)
+	}
+	if h := c.Endpoints(); h[0].State != ConnStateConnected || h[0].Sent != 1 || h[1].Sent != 1 {
+		t.Errorf("unexpected health %+v", h)
+	}
+}
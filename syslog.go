@@ -0,0 +1,219 @@
+package logger
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	syslogTimeLayout5424 = "2006-01-02T15:04:05.000000Z07:00"
+	syslogTimeLayout3164 = time.Stamp
+	syslogNil            = "-"
+)
+
+// SyslogFormat syslog 消息格式
+type SyslogFormat int8
+
+const (
+	RFC5424 SyslogFormat = 0
+	RFC3164 SyslogFormat = 1
+)
+
+// syslog facility
+const (
+	FacilityKern   = 0
+	FacilityUser   = 1
+	FacilityDaemon = 3
+	FacilityLocal0 = 16
+	FacilityLocal1 = 17
+	FacilityLocal2 = 18
+	FacilityLocal3 = 19
+	FacilityLocal4 = 20
+	FacilityLocal5 = 21
+	FacilityLocal6 = 22
+	FacilityLocal7 = 23
+)
+
+// 日志等级对应的 syslog severity
+var syslogSeverity = map[Level]int{
+	LevelDebug: 7, // debug
+	LevelTrace: 7, // debug
+	LevelInfo:  6, // informational
+	LevelWarn:  4, // warning
+	LevelAlert: 4, // warning
+	LevelError: 3, // error
+	LevelPanic: 2, // critical
+	LevelFatal: 1, // alert
+}
+
+// SyslogSeverity 日志等级对应的 syslog severity
+func SyslogSeverity(l Level) int {
+	if v, ok := syslogSeverity[l]; ok {
+		return v
+	}
+	return 6
+}
+
+// NewSyslog 创建 syslog 输出，network 支持 udp, tcp, tls, unixgram, unix
+// network 和 address 为空时使用本机的 unixgram:///dev/log
+func NewSyslog(network, address string) *Syslog {
+	if network == "" {
+		network = "unixgram"
+	}
+	if address == "" && strings.HasPrefix(network, "unix") {
+		address = "/dev/log"
+	}
+	s := &Syslog{Conn: &Conn{Network: network, Address: address}}
+	s.Facility = FacilityUser
+	s.AppName = filepath.Base(os.Args[0])
+	s.Hostname, _ = os.Hostname()
+	s.Conn.pack = s.pack
+	return s
+}
+
+// Syslog syslog 输出(RFC 5424, RFC 3164)，连接、重连和队列由 Conn 负责
+// tcp, tls, unix 使用 octet-counting 分帧(RFC 6587)，udp, unixgram 每条日志一个数据报
+// 需要在首次写入前完成配置
+type Syslog struct {
+	*Conn
+	Format   SyslogFormat `json:"format"`   //消息格式，默认 RFC5424
+	Facility int          `json:"facility"` //默认 FacilityUser
+	AppName  string       `json:"appName"`  //默认程序名
+	Hostname string       `json:"hostname"` //默认本机主机名
+	SDID     string       `json:"sdid"`     //RFC5424 结构化数据ID，默认 fields@32473
+}
+
+func (s *Syslog) Name() string {
+	return "syslog+" + s.Conn.Name()
+}
+
+// pack 编码并按网络类型分帧
+func (s *Syslog) pack(buf *Buffer, msg *Message) {
+	switch s.Network {
+	case "udp", "udp4", "udp6", "unixgram":
+		_ = s.Encode(buf, msg)
+	default:
+		b := NewBuffer()
+		defer b.Free()
+		_ = s.Encode(b, msg)
+		buf.AppendInt(int64(b.Len()))
+		buf.WriteByte(' ')
+		buf.Write(b.B)
+	}
+}
+
+// Encode 按 Format 编码，不包含分帧
+func (s *Syslog) Encode(buf *Buffer, msg *Message) error {
+	buf.WriteByte('<')
+	buf.AppendInt(int64(s.Facility*8 + SyslogSeverity(msg.Level)))
+	buf.WriteByte('>')
+	if s.Format == RFC3164 {
+		s.encode3164(buf, msg)
+	} else {
+		s.encode5424(buf, msg)
+	}
+	return nil
+}
+
+// encode5424 VERSION TIMESTAMP HOSTNAME APP-NAME PROCID MSGID STRUCTURED-DATA MSG
+func (s *Syslog) encode5424(buf *Buffer, msg *Message) {
+	buf.WriteString("1 ")
+	buf.AppendTime(msg.Time, syslogTimeLayout5424)
+	buf.WriteByte(' ')
+	writeSyslogHeader(buf, s.Hostname, 255)
+	buf.WriteByte(' ')
+	writeSyslogHeader(buf, s.AppName, 48)
+	buf.WriteByte(' ')
+	buf.AppendInt(int64(os.Getpid()))
+	buf.WriteByte(' ')
+	writeSyslogHeader(buf, msg.Name, 32)
+	buf.WriteByte(' ')
+	if msg.Path == "" && len(msg.Fields) == 0 {
+		buf.WriteString(syslogNil)
+	} else {
+		sdid := s.SDID
+		if sdid == "" {
+			sdid = "fields@32473"
+		}
+		buf.WriteByte('[')
+		buf.WriteString(sdid)
+		if msg.Path != "" {
+			writeSyslogParam(buf, "path", msg.Path)
+		}
+		for _, f := range msg.Fields {
+			writeSyslogParam(buf, f.Key, f.String())
+		}
+		buf.WriteByte(']')
+	}
+	buf.WriteByte(' ')
+	buf.WriteString(msg.Content)
+	if msg.Stack != "" {
+		buf.WriteByte('\n')
+		buf.WriteString(strings.TrimRight(msg.Stack, "\n"))
+	}
+}
+
+// encode3164 TIMESTAMP HOSTNAME TAG[PID]: MSG, 字段以 key=value 形式追加到消息中
+func (s *Syslog) encode3164(buf *Buffer, msg *Message) {
+	buf.AppendTime(msg.Time, syslogTimeLayout3164)
+	buf.WriteByte(' ')
+	writeSyslogHeader(buf, s.Hostname, 255)
+	buf.WriteByte(' ')
+	tag := s.AppName
+	if len(tag) > 32 {
+		tag = tag[:32]
+	}
+	writeSyslogHeader(buf, tag, 32)
+	buf.WriteByte('[')
+	buf.AppendInt(int64(os.Getpid()))
+	buf.WriteString("]: ")
+	if msg.Path != "" {
+		buf.WriteByte('[')
+		buf.WriteString(msg.Path)
+		buf.WriteString("] ")
+	}
+	msg.writeContent(buf)
+	if msg.Stack != "" {
+		buf.WriteByte('\n')
+		buf.WriteString(strings.TrimRight(msg.Stack, "\n"))
+	}
+}
+
+// writeSyslogHeader 写入头部字段，只保留可打印ASCII字符，空值使用 -
+func writeSyslogHeader(buf *Buffer, s string, limit int) {
+	n := 0
+	for i := 0; i < len(s) && n < limit; i++ {
+		if c := s[i]; c > 32 && c < 127 {
+			buf.WriteByte(c)
+			n++
+		}
+	}
+	if n == 0 {
+		buf.WriteString(syslogNil)
+	}
+}
+
+// writeSyslogParam 写入结构化数据参数 name="value"，名称中的非法字符替换为 _
+func writeSyslogParam(buf *Buffer, name, value string) {
+	buf.WriteByte(' ')
+	if name == "" {
+		name = "_"
+	}
+	for i := 0; i < len(name) && i < 32; i++ {
+		c := name[i]
+		if c <= 32 || c >= 127 || c == '=' || c == ']' || c == '"' {
+			c = '_'
+		}
+		buf.WriteByte(c)
+	}
+	buf.WriteString(`="`)
+	for i := 0; i < len(value); i++ {
+		if c := value[i]; c == '"' || c == '\\' || c == ']' {
+			buf.WriteByte('\\')
+		}
+		buf.WriteByte(value[i])
+	}
+	buf.WriteByte('"')
+}
@@ -0,0 +1,135 @@
+package logger
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestSyslogEncode(t *testing.T) {
+	s := NewSyslog("udp", "127.0.0.1:514")
+	s.Hostname = "host"
+	s.AppName = "app"
+	s.Facility = FacilityLocal0
+	pid := strconv.Itoa(os.Getpid())
+
+	buf := NewBuffer()
+	defer buf.Free()
+	msg := newEncoderMessage()
+	msg.Time = msg.Time.Add(123456 * time.Microsecond)
+	msg.Fields = append(msg.Fields, String("quote", `a"b]`), String("bad key", "v"))
+	_ = s.Encode(buf, msg)
+	want := `<132>1 2024-05-06T07:08:09.123456Z host app ` + pid + ` game [fields@32473 path="game/room.go:12" rid="3" tag="a b" quote="a\"b\]" bad_key="v"] slow room`
+	if buf.String() != want {
+		t.Errorf("RFC5424:\n got %s\nwant %s", buf, want)
+	}
+
+	buf.Reset()
+	s.Format = RFC3164
+	msg = newEncoderMessage()
+	msg.Level = LevelError
+	msg.Fields = nil
+	_ = s.Encode(buf, msg)
+	want = `<131>May  6 07:08:09 host app[` + pid + `]: [game/room.go:12] game: slow room`
+	if buf.String() != want {
+		t.Errorf("RFC3164:\n got %s\nwant %s", buf, want)
+	}
+}
+
+var syslogPattern = regexp.MustCompile(`^<14>1 \S+ \S+ \S+ \d+ - - hello$`)
+
+func TestSyslogUDP(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer pc.Close()
+	s := NewSyslog("udp", pc.LocalAddr().String())
+	defer s.Close()
+	s.Write(&Message{Level: LevelInfo, Time: time.Now(), Content: "hello"})
+
+	_ = pc.SetReadDeadline(time.Now().Add(3 * time.Second))
+	p := make([]byte, 2048)
+	n, _, err := pc.ReadFrom(p)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if !syslogPattern.Match(p[:n]) {
+		t.Errorf("unexpected packet %q", p[:n])
+	}
+}
+
+func TestSyslogTCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer ln.Close()
+	frames := make(chan string, 10)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		for {
+			// octet-counting: LEN SP MSG
+			size, err := r.ReadString(' ')
+			if err != nil {
+				return
+			}
+			n, _ := strconv.Atoi(size[:len(size)-1])
+			p := make([]byte, n)
+			if _, err = io.ReadFull(r, p); err != nil {
+				return
+			}
+			frames <- string(p)
+		}
+	}()
+	s := NewSyslog("tcp", ln.Addr().String())
+	defer s.Close()
+	for i := 0; i < 3; i++ {
+		s.Write(&Message{Level: LevelInfo, Time: time.Now(), Content: "hello"})
+	}
+	for i := 0; i < 3; i++ {
+		if frame := receiveLine(t, frames); !syslogPattern.MatchString(frame) {
+			t.Errorf("unexpected frame %q", frame)
+		}
+	}
+}
+
+func TestSyslogUnixgram(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("unixgram not supported")
+	}
+	path := filepath.Join(t.TempDir(), "log.sock")
+	pc, err := net.ListenPacket("unixgram", path)
+	if err != nil {
+		t.Skipf("listen unixgram: %v", err)
+	}
+	defer pc.Close()
+	s := NewSyslog("", path)
+	defer s.Close()
+	if s.Name() != "syslog+unixgram://"+path {
+		t.Errorf("Name = %v", s.Name())
+	}
+	s.Write(&Message{Level: LevelInfo, Time: time.Now(), Content: "hello"})
+
+	_ = pc.SetReadDeadline(time.Now().Add(3 * time.Second))
+	p := make([]byte, 2048)
+	n, _, err := pc.ReadFrom(p)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if !syslogPattern.Match(p[:n]) {
+		t.Errorf("unexpected packet %q", p[:n])
+	}
+}
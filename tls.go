@@ -0,0 +1,60 @@
+package logger
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"slices"
+)
+
+const networkTLS = "tls" // Conn.Network 为 tls 时使用 TCP + TLS 连接
+
+// TLSOptions Conn 的TLS配置，文件和对象同时设置时都会生效
+type TLSOptions struct {
+	CAFile             string            `json:"caFile"`     //CA证书文件(PEM)，未设置CA时使用系统证书
+	CertFile           string            `json:"certFile"`   //客户端证书文件(PEM)，用于mTLS
+	KeyFile            string            `json:"keyFile"`    //客户端私钥文件(PEM)
+	ServerName         string            `json:"serverName"` //服务器名称，默认使用地址中的主机名
+	MinVersion         uint16            `json:"minVersion"` //最低TLS版本，默认 tls.VersionTLS12
+	InsecureSkipVerify bool              `json:"insecureSkipVerify"`
+	RootCAs            *x509.CertPool    `json:"-"` //CA证书池
+	Certificates       []tls.Certificate `json:"-"` //客户端证书
+}
+
+// Config 生成 tls.Config
+func (o *TLSOptions) Config() (*tls.Config, error) {
+	config := &tls.Config{
+		ServerName:         o.ServerName,
+		MinVersion:         o.MinVersion,
+		RootCAs:            o.RootCAs,
+		Certificates:       o.Certificates,
+		InsecureSkipVerify: o.InsecureSkipVerify,
+	}
+	if config.MinVersion == 0 {
+		config.MinVersion = tls.VersionTLS12
+	}
+	if o.CAFile != "" {
+		data, err := os.ReadFile(o.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		// 复制证书池，避免修改调用方传入的 RootCAs
+		if config.RootCAs == nil {
+			config.RootCAs = x509.NewCertPool()
+		} else {
+			config.RootCAs = config.RootCAs.Clone()
+		}
+		if !config.RootCAs.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("no valid certificate in ca file:%v", o.CAFile)
+		}
+	}
+	if o.CertFile != "" || o.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(o.CertFile, o.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		config.Certificates = append(slices.Clone(o.Certificates), cert)
+	}
+	return config, nil
+}
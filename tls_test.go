@@ -0,0 +1,199 @@
+package logger
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type testCert struct {
+	cert    *x509.Certificate
+	key     *ecdsa.PrivateKey
+	certPEM []byte
+	keyPEM  []byte
+}
+
+func (c *testCert) tlsCertificate(t *testing.T) tls.Certificate {
+	cert, err := tls.X509KeyPair(c.certPEM, c.keyPEM)
+	if err != nil {
+		t.Fatalf("X509KeyPair: %v", err)
+	}
+	return cert
+}
+
+// newTestCert 生成证书，parent 为空时生成自签名的CA证书
+func newTestCert(t *testing.T, name string, parent *testCert, usage x509.ExtKeyUsage) *testCert {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	serial, _ := rand.Int(rand.Reader, big.NewInt(1<<62))
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		DNSNames:     []string{name},
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+	}
+	signer, signerKey := template, key
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+	} else {
+		template.ExtKeyUsage = []x509.ExtKeyUsage{usage}
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatalf("CreateCertificate: %v", err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	keyDER, _ := x509.MarshalECPrivateKey(key)
+	return &testCert{
+		cert:    cert,
+		key:     key,
+		certPEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		keyPEM:  pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+	}
+}
+
+func TestConnTLS(t *testing.T) {
+	ca := newTestCert(t, "ca", nil, 0)
+	server := newTestCert(t, "localhost", ca, x509.ExtKeyUsageServerAuth)
+	client := newTestCert(t, "client", ca, x509.ExtKeyUsageClientAuth)
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{server.tlsCertificate(t)},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    pool,
+		MinVersion:   tls.VersionTLS12,
+	})
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer ln.Close()
+	lines := make(chan string, 10)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				scanner := bufio.NewScanner(conn)
+				for scanner.Scan() {
+					lines <- scanner.Text()
+				}
+			}(conn)
+		}
+	}()
+
+	// 使用证书文件配置mTLS
+	dir := t.TempDir()
+	files := map[string][]byte{"ca.pem": ca.certPEM, "client.pem": client.certPEM, "client.key": client.keyPEM}
+	for name, data := range files {
+		if err = os.WriteFile(filepath.Join(dir, name), data, 0600); err != nil {
+			t.Fatalf("write %v: %v", name, err)
+		}
+	}
+	c := &Conn{
+		Network: "tls",
+		Address: ln.Addr().String(),
+		TLS: &TLSOptions{
+			CAFile:     filepath.Join(dir, "ca.pem"),
+			CertFile:   filepath.Join(dir, "client.pem"),
+			KeyFile:    filepath.Join(dir, "client.key"),
+			ServerName: "localhost",
+			MinVersion: tls.VersionTLS13,
+		},
+	}
+	defer c.Close()
+	if c.Name() != "tls://"+ln.Addr().String() {
+		t.Errorf("Name = %v", c.Name())
+	}
+	c.Write(&Message{Content: "secret"})
+	if line := receiveLine(t, lines); line != "secret" {
+		t.Fatalf("unexpected line %q", line)
+	}
+
+	// 未配置CA时证书校验失败
+	states := make(chan error, 1)
+	bad := &Conn{
+		Network: "tls",
+		Address: ln.Addr().String(),
+		TLS:     &TLSOptions{ServerName: "localhost", Certificates: []tls.Certificate{client.tlsCertificate(t)}},
+		Policy:  BackoffDrop,
+		OnState: func(_ string, _ ConnState, err error) { states <- err },
+	}
+	defer bad.Close()
+	bad.Write(&Message{Content: "rejected"})
+	select {
+	case err = <-states:
+		var unknown x509.UnknownAuthorityError
+		if !errors.As(err, &unknown) {
+			t.Errorf("unexpected error %v", err)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatalf("state callback timeout")
+	}
+}
+
+func TestTLSOptionsConfig(t *testing.T) {
+	ca := newTestCert(t, "ca", nil, 0)
+	other := newTestCert(t, "other", nil, 0)
+	client := newTestCert(t, "client", ca, x509.ExtKeyUsageClientAuth)
+	dir := t.TempDir()
+	files := map[string][]byte{"ca.pem": ca.certPEM, "client.pem": client.certPEM, "client.key": client.keyPEM}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0600); err != nil {
+			t.Fatalf("write %v: %v", name, err)
+		}
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(other.cert)
+	certs := make([]tls.Certificate, 1, 4)
+	certs[0] = other.tlsCertificate(t)
+	opts := &TLSOptions{
+		CAFile:       filepath.Join(dir, "ca.pem"),
+		CertFile:     filepath.Join(dir, "client.pem"),
+		KeyFile:      filepath.Join(dir, "client.key"),
+		RootCAs:      pool,
+		Certificates: certs,
+	}
+	for i := 0; i < 2; i++ {
+		config, err := opts.Config()
+		if err != nil {
+			t.Fatalf("Config: %v", err)
+		}
+		if len(config.Certificates) != 2 {
+			t.Fatalf("Certificates = %d", len(config.Certificates))
+		}
+		if _, err = ca.cert.Verify(x509.VerifyOptions{Roots: config.RootCAs}); err != nil {
+			t.Fatalf("CA file not added to RootCAs: %v", err)
+		}
+	}
+	// 调用方传入的证书池和证书列表保持不变
+	if _, err := ca.cert.Verify(x509.VerifyOptions{Roots: pool}); err == nil {
+		t.Errorf("RootCAs was modified")
+	}
+	if len(opts.Certificates) != 1 || len(certs[:2][1].Certificate) != 0 {
+		t.Errorf("Certificates was modified")
+	}
+}